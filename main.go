@@ -6,51 +6,99 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"runtime"
+	"syscall"
 	"time"
 
+	"github.com/nstratos/mdt/session"
+	"github.com/nstratos/mdt/sessionlog"
 	"github.com/nstratos/mdt/ui"
 
 	"github.com/nsf/termbox-go"
 )
 
-// Global holder of captured key presses.
-var captures = make([]ui.Capture, 0)
-
 var (
 	version     = "devel"
 	showVersion = flag.Bool("v", false, "print program version and exit")
 )
 
-// Logs to .txt file in program's directory, named: S-E hz day date month time
-// where S is start hz and E is end hz, e.g. '15-19 hz wed 27 dec 22.09.txt'
-func logCaptures() error {
-	c := ui.GetConfig()
-	if len(captures) == 0 {
+// logCaptures writes the log of a session in the configured output directory
+// and formats. Sessions without captures are not logged.
+func logCaptures(r session.Record) error {
+	if len(r.Captures) == 0 {
 		return nil
 	}
-	format := "Mon 02 Jan 15.04"
-	filename := fmt.Sprintf("%v-%v hz %v", c.StartHz, c.EndHz, time.Now().Format(format))
-	f, err := os.Create(filename + ".txt")
-	if err != nil {
+	c := ui.GetConfig()
+	out := sessionlog.Output{Dir: c.OutputDir, Template: c.FilenameTemplate, Version: version}
+	_, err := out.Write(r, c.Formats...)
+	return err
+}
+
+// endSession logs a session that has been stopped and then removes its
+// journal. If the log could not be written, the journal is kept, so that the
+// session is logged before the next one starts, and the error is returned.
+func endSession(s *session.Session, j *session.Journal) error {
+	if err := logCaptures(s.Record()); err != nil {
+		if j != nil {
+			j.Close()
+		}
 		return err
 	}
-	_, err = f.WriteString(fmt.Sprintf("%v\r\nMode: %v\r\n", filename, c.Mode))
+	if j != nil {
+		if err := j.Remove(); err != nil {
+			ui.Debug(fmt.Sprintf("Error removing journal: %v", err))
+		}
+	}
+	return nil
+}
+
+// findJournal looks for the journal of a session that did not finish
+// properly and if it finds one, it offers to recover it. Journals without
+// captures have nothing to recover and are removed.
+func findJournal(path string) *session.Record {
+	r, err := session.ReadJournal(path)
 	if err != nil {
-		return err
+		if err == session.ErrEmptyJournal {
+			os.Remove(path)
+		} else if !os.IsNotExist(err) {
+			ui.Debug(fmt.Sprintf("Error reading journal: %v", err))
+		}
+		return nil
+	}
+	if len(r.Captures) == 0 {
+		os.Remove(path)
+		return nil
 	}
-	for _, capt := range captures {
-		_, err = f.WriteString(
-			fmt.Sprintf("%.2fhz @ %.2f base hz, on %v %v\r\n",
-				capt.Hz, c.BaseHz, capt.Timestamp(), capt.Label()))
-		if err != nil {
-			return err
+	ui.UpdateText(fmt.Sprintf("Unfinished session of %v found, press 'r' to recover it.",
+		r.Started.Format("Mon 02 Jan 15:04")))
+	return &r
+}
+
+// keepJournal makes sure that the journal left at path by a session that was
+// not logged, e.g. one that was not recovered, is not discarded when a new
+// session creates its journal. The session is logged as recovered or, if that
+// fails, the journal is renamed after the current time and its new name is
+// returned along with the reason. An error is returned only if the journal
+// is still at path.
+func keepJournal(path string) (kept string, reason, err error) {
+	r, err := session.ReadJournal(path)
+	switch {
+	case os.IsNotExist(err):
+		return "", nil, nil
+	case err == session.ErrEmptyJournal:
+		return "", nil, os.Remove(path)
+	case err == nil:
+		if err = logCaptures(r); err == nil {
+			return "", nil, os.Remove(path)
 		}
 	}
-	// Emptying capture holder.
-	captures = nil
-	captures = make([]ui.Capture, 0)
-	return nil
+	kept = fmt.Sprintf("%v.%v", path, time.Now().Format("20060102-150405"))
+	if rerr := os.Rename(path, kept); rerr != nil {
+		return "", nil, rerr
+	}
+	return kept, err, nil
 }
 
 func main() {
@@ -59,6 +107,9 @@ func main() {
 		fmt.Printf("%s %s (runtime: %s)\n", os.Args[0], version, runtime.Version())
 		os.Exit(0)
 	}
+	if flag.Arg(0) == "stats" {
+		os.Exit(runStats(flag.Args()[1:]))
+	}
 
 	if err := ui.Init(version); err != nil {
 		log.Println("Could not initialize: ", err)
@@ -71,49 +122,232 @@ func main() {
 	ui.DrawAll()
 	defer ui.Close()
 
+	journalPath, err := ui.JournalPath()
+	if err != nil {
+		ui.Debug(fmt.Sprintf("Error finding journal: %v", err))
+	}
+	var unfinished *session.Record
+	if journalPath != "" {
+		unfinished = findJournal(journalPath)
+	}
+
 	letter := make(chan rune)
 	input := make(chan *ui.Entry)
 	start := make(chan bool)
+	pause := make(chan bool)
+	recovery := make(chan bool)
 	done := make(chan bool)
-	endTimer := make(chan bool)
-	defer close(letter)
-	defer close(input)
-	defer close(start)
-	defer close(done)
-	defer close(endTimer)
-	go captureEvents(letter, input, start, done)
+	interrupt := make(chan bool)
+	expired := make(chan bool)
+	undo := make(chan bool)
+	relabel := make(chan bool)
+	go captureEvents(letter, input, start, pause, recovery, done, interrupt, undo, relabel)
+
+	// Closing the terminal or killing the program must not lose a running
+	// session. Ctrl-C does not raise a signal while termbox is on, it is
+	// received as a key instead.
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigs)
+
+	var s *session.Session
+	var j *session.Journal
+	var quitTimer chan struct{}
 	capturing := false
-	timerEnded := false
+	// undoing is true once the undo key has been pressed and until it is
+	// pressed again to confirm. relabeling is true once the relabel key has
+	// been pressed and until the key of the new label is pressed.
+	undoing, relabeling := false, false
+	// stop stops the running session, waits for the timer goroutine to
+	// return and logs the session. It returns the error of writing the log.
+	stop := func(reason session.EndReason) error {
+		capturing = false
+		undoing, relabeling = false, false
+		ui.LockInputs(false)
+		s.Stop(reason)
+		close(quitTimer)
+		<-expired
+		ui.ResetPart()
+		ui.HighlightOpen(nil)
+		return endSession(s, j)
+	}
+	// abortErr is the error of logging the session that was running when
+	// the program was quit. It is printed once the screen is closed.
+	var abortErr error
 loop:
 	for {
 		select {
 		case <-start:
 			ui.DeselectAllInputs()
-			capturing = !capturing
 			if capturing {
-				c := ui.GetConfig()
-				go timer(c.TotalTime*60, c.Offset*60, letter, endTimer)
-				timerEnded = false
-			}
-			if !capturing && !timerEnded {
-				endTimer <- true
-				if err := logCaptures(); err != nil {
-					ui.Debug(fmt.Sprintf("Error logging to txt file: %v", err))
+				if err := stop(session.EndStopped); err != nil {
+					ui.UpdateText(fmt.Sprintf("Session stopped, could not write log (%v)", err))
+					continue
 				}
+				ui.UpdateText("Session stopped manually.")
+				continue
+			}
+			// The session runs with a copy of the configuration, or of
+			// the presets of the active playlist, which cannot be changed
+			// until the session ends.
+			c := ui.GetConfig()
+			sc := c.Session()
+			if c.Playlist != "" {
+				if sc, err = c.PlaylistSession(); err != nil {
+					ui.UpdateText(fmt.Sprintf("%v", err))
+					continue
+				}
+			}
+			// The journal of a session that was not logged, because it
+			// was not recovered or its log could not be written, is kept
+			// before the new journal is created. If it has to be renamed,
+			// the session does not start so that the user can see where.
+			if journalPath != "" {
+				kept, reason, err := keepJournal(journalPath)
+				if err != nil {
+					ui.UpdateText(fmt.Sprintf("Could not keep unfinished session (%v)", err))
+					continue
+				}
+				if kept != "" {
+					unfinished = nil
+					ui.UpdateText(fmt.Sprintf("Unfinished session kept as %v", filepath.Base(kept)))
+					ui.Debug(fmt.Sprintf("Could not log it (%v), press 'space' to start.", reason))
+					continue
+				}
+			}
+			capturing = true
+			ui.LockInputs(true)
+			s = session.New(sc, session.SystemClock)
+			unfinished = nil
+			j = nil
+			if journalPath != "" {
+				if j, err = session.CreateJournal(journalPath); err != nil {
+					ui.Debug(fmt.Sprintf("Error creating journal: %v", err))
+					j = nil
+				}
+			}
+			if j != nil {
+				s.SetJournal(j)
+			}
+			s.Start()
+			quitTimer = make(chan struct{})
+			go timer(s, quitTimer, expired)
+		case <-pause:
+			// Pausing only makes sense while the timer is on.
+			if !capturing {
+				continue
 			}
-		case timerEnded, _ = <-endTimer:
+			if !s.Paused() {
+				s.Pause()
+				ui.UpdateText(fmt.Sprintf("PAUSED on %v, press 'p' to resume.", session.FormatElapsed(s.Elapsed())))
+			} else {
+				s.Resume()
+				ui.UpdateText("Session resumed, press 'space' to stop, 'p' to pause, 'Esc' to quit.")
+			}
+		case <-expired:
 			capturing = false
-			if err := logCaptures(); err != nil {
-				ui.Debug(fmt.Sprintf("Error logging to txt file: %v", err))
+			undoing, relabeling = false, false
+			ui.LockInputs(false)
+			s.Stop(session.EndExpired)
+			ui.ResetPart()
+			ui.HighlightOpen(nil)
+			if err := endSession(s, j); err != nil {
+				ui.UpdateText(fmt.Sprintf("Session ended, could not write log (%v)", err))
+				continue
+			}
+			ui.UpdateText("Session ended.")
+		case <-recovery:
+			if capturing || unfinished == nil {
+				continue
+			}
+			if err := logCaptures(*unfinished); err != nil {
+				ui.UpdateText(fmt.Sprintf("Could not recover session (%v)", err))
+				continue
 			}
+			if err := os.Remove(journalPath); err != nil {
+				ui.Debug(fmt.Sprintf("Error removing journal: %v", err))
+			}
+			unfinished = nil
+			ui.UpdateText("Unfinished session recovered and logged.")
 		case l := <-letter:
-			// If the timer is on, we keep resending the letter to the channel so
-			// that it will be eventually captured by the timer. If the timer is
-			// not on, we discard the letter. Without this case the channel would
-			// block forever if it was sent a letter without timer to consume it.
-			if capturing {
-				letter <- l
+			// If the timer is not on, we discard the letter.
+			if !capturing {
+				continue
+			}
+			undoing = false
+			if relabeling {
+				relabeling = false
+				c, err := s.Relabel(l)
+				if err != nil {
+					ui.UpdateText(fmt.Sprintf("Could not relabel (%v)", err))
+					continue
+				}
+				ui.UpdateText(fmt.Sprintf("Relabeled %v.", ui.CaptureText(c)))
+				ui.HighlightOpen(s.OpenIntervals())
+				if err := s.JournalErr(); err != nil {
+					ui.Debug(fmt.Sprintf("Error writing to journal: %v", err))
+				}
+				continue
+			}
+			c, err := s.Capture(l)
+			switch err {
+			case nil:
+				if c.Interval {
+					ui.UpdateText(ui.IntervalText(c, s.Config()))
+					ui.HighlightOpen(s.OpenIntervals())
+				} else {
+					ui.UpdateText(ui.RecordedKeyText(c, s.Config()))
+				}
+				if err := s.JournalErr(); err != nil {
+					ui.Debug(fmt.Sprintf("Error writing to journal: %v", err))
+				}
+			case session.ErrPaused:
+				ui.UpdateText("Session is paused, press 'p' to resume.")
+			}
+		case <-undo:
+			if !capturing {
+				continue
+			}
+			relabeling = false
+			c, ok := s.LastCapture()
+			if !ok {
+				ui.UpdateText("No capture to undo.")
+				continue
+			}
+			// The capture is removed, or the interval that the last key
+			// press closed is opened again, only when the key is pressed
+			// again.
+			verb, past := "Undo", "Undid"
+			if c.End != nil {
+				verb, past = "Reopen", "Reopened"
+			}
+			if !undoing {
+				undoing = true
+				ui.UpdateText(fmt.Sprintf("%v %v? Ctrl-Z to confirm.", verb, ui.CaptureText(c)))
+				continue
 			}
+			undoing = false
+			if _, err := s.Undo(); err != nil {
+				ui.UpdateText(fmt.Sprintf("Could not undo (%v)", err))
+				continue
+			}
+			ui.UpdateText(fmt.Sprintf("%v %v.", past, ui.CaptureText(c)))
+			ui.HighlightOpen(s.OpenIntervals())
+			if err := s.JournalErr(); err != nil {
+				ui.Debug(fmt.Sprintf("Error writing to journal: %v", err))
+			}
+		case <-relabel:
+			if !capturing {
+				continue
+			}
+			undoing = false
+			c, ok := s.LastCapture()
+			if !ok {
+				ui.UpdateText("No capture to relabel.")
+				continue
+			}
+			relabeling = true
+			ui.UpdateText(fmt.Sprintf("Relabel %v as? Press its key.", ui.CaptureText(c)))
 		case in := <-input:
 			if si := ui.SelectedInput(); si != nil {
 				if in.Enter {
@@ -148,81 +382,187 @@ loop:
 				}
 			}
 		case <-done:
-			if ui.SelectedInput() == nil {
-				break loop
+			// Esc leaves the input being edited, then the focused input
+			// and then the program.
+			if ui.SelectedInput() != nil {
+				ui.DeselectAllInputs()
+				ui.ResetText()
+				continue
 			}
-			ui.DeselectAllInputs()
-			ui.ResetText()
+			if ui.FocusedInput() != nil {
+				ui.ClearFocus()
+				ui.ResetText()
+				continue
+			}
+			if undoing || relabeling {
+				undoing, relabeling = false, false
+				ui.UpdateText("Correction cancelled.")
+				continue
+			}
+			if capturing {
+				abortErr = stop(session.EndAborted)
+			}
+			break loop
+		case <-interrupt:
+			if capturing {
+				abortErr = stop(session.EndAborted)
+			}
+			break loop
+		case <-sigs:
+			if capturing {
+				abortErr = stop(session.EndAborted)
+			}
+			break loop
 		}
 	}
+	if abortErr != nil {
+		ui.Close()
+		fmt.Fprintf(os.Stderr, "Session aborted, could not write log (%v)\n", abortErr)
+		os.Exit(1)
+	}
 }
 
-func timer(maxSeconds, offsetSeconds int, letter chan rune, end chan bool) {
-	seconds := 0
-	expired := time.NewTimer(time.Second * time.Duration(maxSeconds)).C
-	tick := time.NewTicker(time.Second).C
-	ui.UpdateTimer(seconds)
-	ui.UpdateText("New Session started, press 'space' to stop, 'Esc' to quit.")
-	ui.Debug(fmt.Sprintf("Key Capturing starts in %v", ui.FormatTimer(offsetSeconds)))
+// timer runs for as long as a session is on. It refreshes the timer on the
+// screen and notifies when the session's total time has passed. It returns
+// when quit is closed. The elapsed time is kept by the session itself, the
+// expiration timer and the ticker are only used to know when to refresh the
+// screen or end the session. The parts of a playlist session are shown as
+// they start and the cues are announced as they are reached.
+func timer(s *session.Session, quit chan struct{}, expired chan bool) {
+	c := s.Config()
+	offset := c.Offset
+	part := 0
+	cues := s.Cues()
+	var last time.Duration
+	expiration := time.NewTimer(s.Remaining())
+	defer expiration.Stop()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	ui.UpdateTimer(0)
+	ui.UpdateText("New Session started, press 'space' to stop, 'p' to pause, 'Esc' to quit.")
+	ui.Debug(fmt.Sprintf("Key Capturing starts in %v", ui.FormatTimer(int(offset.Seconds()))))
+	if n := c.PartCount(); n > 1 {
+		ui.UpdatePart(1, n)
+	}
 	for {
 		select {
-		case l := <-letter:
-			// If user has set an offset it means that we have to wait for that amount
-			// of seconds. Thus unless it reaches 0 we ignore label keypresses.
-			if offsetSeconds == 0 {
-				capture := ui.Capture{Value: l, Seconds: seconds, Hz: ui.CurrentHz(seconds)}
-				captures = append(captures, capture)
-				ui.UpdateText(ui.RecordedKeyText(l, seconds))
-			}
-		case <-end:
-			ui.UpdateText("Session stopped manually.")
+		case <-quit:
+			expired <- false
 			return
-		case <-expired:
-			end <- true
-			ui.UpdateText("Session ended.")
+		case <-expiration.C:
+			// Time spent paused postpones the end of the session.
+			if r := s.Remaining(); r > 0 {
+				expiration.Reset(r)
+				continue
+			}
+			select {
+			case expired <- true:
+			case <-quit:
+				expired <- false
+			}
 			return
-		case <-tick:
-			seconds++
-			ui.UpdateTimer(seconds)
-			if offsetSeconds == 0 {
+		case <-ticker.C:
+			e := s.Elapsed()
+			ui.UpdateTimer(int(e.Seconds()))
+			for _, cue := range cues {
+				if cue.Elapsed > last && cue.Elapsed <= e {
+					if err := ui.Cue(cue); err != nil {
+						ui.UpdateText(fmt.Sprintf("Could not announce cue (%v)", err))
+					}
+				}
+			}
+			last = e
+			i, t := c.PartAt(e)
+			pc := c.PartConfig(i)
+			if n := c.PartCount(); n > 1 && i != part {
+				part = i
+				ui.UpdatePart(i+1, n)
+				ui.UpdateText(fmt.Sprintf("Part %d/%d (%v), press 'space' to stop, 'p' to pause, 'Esc' to quit.", i+1, n, pc.Preset))
+			}
+			switch m, ok := s.NextMilestone(); {
+			case t < 0:
+				ui.Debug(fmt.Sprintf("Part %d/%d starts in %v", i+1, c.PartCount(), ui.FormatTimer(int((-t).Seconds()))))
+			case t < pc.Offset:
+				ui.Debug(fmt.Sprintf("Key Capturing starts in %v", ui.FormatTimer(int((pc.Offset - t).Seconds()))))
+			case ok:
+				ui.Debug(fmt.Sprintf("%v in %v", m.Event(c), ui.FormatTimer(int((m.Elapsed - e).Seconds()))))
+			default:
 				ui.Debug("Key Capturing has started")
-			} else {
-				offsetSeconds--
-				ui.Debug(fmt.Sprintf("Key Capturing starts in %v", ui.FormatTimer(offsetSeconds)))
 			}
-
 		}
 	}
 }
 
-func captureEvents(letter chan rune, input chan *ui.Entry, start, done chan bool) {
+// navigationKey returns true if a key moves the focus between the inputs.
+func navigationKey(k termbox.Key) bool {
+	return k == termbox.KeyTab || k == ui.KeyShiftTab || k == termbox.KeyArrowDown || k == termbox.KeyArrowUp
+}
+
+func captureEvents(letter chan rune, input chan *ui.Entry, start, pause, recovery, done, interrupt, undo, relabel chan bool) {
 	started := false
 	for {
-		ev := termbox.PollEvent()
+		ev := ui.PollEvent()
+		// While an input has the focus and is not being edited, the
+		// navigation keys, Enter and space act on the inputs.
+		focused := ui.FocusedInput() != nil && ui.SelectedInput() == nil
 		switch {
+		case ui.Prompting() && ev.Type == termbox.EventKey:
+			// A prompt in the status bar takes all keys until it closes.
+			ui.PromptKey(ev)
 		case ev.Key == termbox.KeyEsc:
 			done <- true
+		case ev.Key == termbox.KeyCtrlC:
+			interrupt <- true
+		case navigationKey(ev.Key) && ui.SelectedInput() == nil:
+			if ui.InputsLocked() {
+				ui.UpdateText("Configuration is locked while a session is running.")
+				continue
+			}
+			if ev.Key == termbox.KeyTab || ev.Key == termbox.KeyArrowDown {
+				ui.MoveFocus(1)
+			} else {
+				ui.MoveFocus(-1)
+			}
+		case focused && (ev.Key == termbox.KeyEnter || ev.Key == termbox.KeySpace && ui.FocusedInput().Type == ui.InputSwitch):
+			if err := ui.FocusedInput().Activate(); err != nil {
+				ui.UpdateText(fmt.Sprintf("%v", err))
+			}
+		case focused && !ui.InputsLocked() && ui.PresetAction(ev.Key):
+		case ev.Key == ui.KeyUndo:
+			undo <- true
+		case ev.Key == ui.KeyRelabel:
+			relabel <- true
 		case ev.Key == termbox.KeySpace:
+			// Space on any other focused input leaves the configuration
+			// and starts the session.
+			if focused {
+				ui.ClearFocus()
+			}
 			started = !started
 			start <- started
 		case ui.AllowedEntry(ev):
 			input <- ui.NewEntry(ev)
-		case supportedLabel(ev.Ch):
+		case ev.Ch == ui.KeyPause:
+			pause <- true
+		case ev.Ch == ui.KeyRecover:
+			recovery <- true
+		case ui.LabelKey(ev.Ch):
 			letter <- ev.Ch
 		case ev.Type == termbox.EventResize:
 			ui.DrawAll()
 		case ev.Type == termbox.EventMouse:
 			cell := ui.GetCell(ev.MouseX, ev.MouseY)
+			if cell.Input != nil && ui.InputsLocked() {
+				ui.UpdateText("Configuration is locked while a session is running.")
+				continue
+			}
+			// The mouse takes over from the keyboard.
+			ui.ClearFocus()
 			if cell.Input != nil {
-				if cell.Input.Type == ui.InputSwitch {
-					ui.DeselectAllInputs()
-					if err := cell.Input.Switch(); err != nil {
-						ui.UpdateText(fmt.Sprintf("%v", err))
-						//ui.Debug(fmt.Sprintf("switch. %v", err))
-						continue
-					}
-				} else {
-					cell.Input.SetSelected(true)
+				if err := cell.Input.Activate(); err != nil {
+					ui.UpdateText(fmt.Sprintf("%v", err))
+					//ui.Debug(fmt.Sprintf("switch. %v", err))
+					continue
 				}
 			} else {
 				ui.DeselectAllInputs()
@@ -230,17 +570,3 @@ func captureEvents(letter chan rune, input chan *ui.Entry, start, done chan bool
 		}
 	}
 }
-
-// 'a' = 97	-> visual imagination
-// 'd' = 100	-> language thought
-// 'e' = 101	-> language voice
-// 'q' = 113	-> visual memory
-// 's' = 115	-> auditory imagination
-// 'w' = 119	-> auditory memory
-func supportedLabel(key rune) bool {
-	if key == 'a' || key == 'd' || key == 'e' ||
-		key == 'q' || key == 's' || key == 'w' {
-		return true
-	}
-	return false
-}
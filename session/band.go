@@ -0,0 +1,57 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Band is a brainwave frequency band which starts at FromHz and ends where
+// the next band starts.
+type Band struct {
+	Name   string
+	FromHz float64
+}
+
+// Bands is a list of consecutive bands, sorted by the Hz they start at.
+type Bands []Band
+
+// DefaultBands holds the commonly used brainwave bands.
+var DefaultBands = Bands{
+	{Name: "delta", FromHz: 0},
+	{Name: "theta", FromHz: 4},
+	{Name: "alpha", FromHz: 8},
+	{Name: "beta", FromHz: 13},
+	{Name: "gamma", FromHz: 30},
+}
+
+// Valid returns an error if a band has no name, if two bands have the same
+// name or if the bands are not sorted by the Hz they start at.
+func (b Bands) Valid() error {
+	seen := make(map[string]bool)
+	for i, band := range b {
+		if band.Name == "" {
+			return fmt.Errorf("band %d must have a name", i+1)
+		}
+		if seen[band.Name] {
+			return fmt.Errorf("band %q is defined more than once", band.Name)
+		}
+		seen[band.Name] = true
+		if i > 0 && band.FromHz <= b[i-1].FromHz {
+			return errors.New("bands must be sorted by the Hz they start at")
+		}
+	}
+	return nil
+}
+
+// Classify returns the name of the band that hz falls in. It returns an empty
+// string if hz is lower than the start of the first band.
+func (b Bands) Classify(hz float64) string {
+	name := ""
+	for _, band := range b {
+		if hz < band.FromHz {
+			break
+		}
+		name = band.Name
+	}
+	return name
+}
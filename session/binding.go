@@ -0,0 +1,35 @@
+package session
+
+import "unicode/utf8"
+
+// Binding binds a key to the label of the occurrences it captures. The label
+// can also carry attributes which place it on the axes of a taxonomy, e.g.
+// {"Modality": "Visual", "Process": "Memory"}.
+type Binding struct {
+	Key        string // a single character
+	Label      string
+	Attributes map[string]string `json:",omitempty"`
+	// Interval binds the key in interval mode: its first press opens an
+	// occurrence that lasts until it is pressed again.
+	Interval bool `json:",omitempty"`
+}
+
+// Rune returns the key of the binding as a rune. It returns
+// utf8.RuneError if the key is not a single character.
+func (b Binding) Rune() rune {
+	r, size := utf8.DecodeRuneInString(b.Key)
+	if size != len(b.Key) {
+		return utf8.RuneError
+	}
+	return r
+}
+
+// Binding returns the binding of a key, if it is bound.
+func (c Config) Binding(key rune) (Binding, bool) {
+	for _, b := range c.Keys {
+		if b.Rune() == key {
+			return b, true
+		}
+	}
+	return Binding{}, false
+}
@@ -0,0 +1,149 @@
+package session
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNoCapture is returned when correcting the last capture of a session
+// that has none.
+var ErrNoCapture = errors.New("no capture to correct")
+
+// CorrectionKind is the kind of a correction.
+type CorrectionKind string
+
+// The kinds of corrections.
+const (
+	// CorrectionUndo removes the last capture.
+	CorrectionUndo CorrectionKind = "undo"
+	// CorrectionRelabel changes the label of the last capture.
+	CorrectionRelabel CorrectionKind = "relabel"
+	// CorrectionReopen undoes the closing of an interval, which becomes
+	// open again.
+	CorrectionReopen CorrectionKind = "reopen"
+)
+
+// Correction is a change made to the last capture of a session while it was
+// running, e.g. removing a capture of a mistyped key. The last capture is the
+// one that the last key press opened, closed or captured. Corrections are
+// kept so that the log shows what was changed.
+type Correction struct {
+	Kind    CorrectionKind
+	Time    time.Time     // wall clock time of the correction
+	Elapsed time.Duration // elapsed session time of the correction
+	// Capture is the capture as it was before the correction.
+	Capture Capture
+	// Key, Label and Attributes are those that a relabeled capture got.
+	Key        rune              `json:",omitempty"`
+	Label      string            `json:",omitempty"`
+	Attributes map[string]string `json:",omitempty"`
+}
+
+// apply applies the correction to the captures that it was made on and
+// returns the corrected captures.
+func (c Correction) apply(captures []Capture) []Capture {
+	i := corrected(captures, c.Capture)
+	if i < 0 {
+		return captures
+	}
+	switch c.Kind {
+	case CorrectionUndo:
+		return append(captures[:i], captures[i+1:]...)
+	case CorrectionRelabel:
+		captures[i].Key = c.Key
+		captures[i].Label = c.Label
+		captures[i].Attributes = c.Attributes
+	case CorrectionReopen:
+		captures[i].End = nil
+	}
+	return captures
+}
+
+// corrected returns the index of the capture that a correction was made on,
+// found by its key and its time, or -1 if there is none.
+func corrected(captures []Capture, c Capture) int {
+	for i := len(captures) - 1; i >= 0; i-- {
+		if captures[i].Key == c.Key && captures[i].Elapsed == c.Elapsed {
+			return i
+		}
+	}
+	return -1
+}
+
+// LastCapture returns the capture of the last key press of the session, which
+// has an End if the press closed an interval. It returns false if there is
+// none.
+func (s *Session) LastCapture() (Capture, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.actions) == 0 {
+		return Capture{}, false
+	}
+	return s.captures[s.actions[len(s.actions)-1]], true
+}
+
+// Undo undoes the last key press of a running session and returns the
+// capture as it was before. A capture is removed, while a closed interval is
+// opened again.
+func (s *Session) Undo() (Capture, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.running {
+		return Capture{}, ErrNotRunning
+	}
+	if len(s.actions) == 0 {
+		return Capture{}, ErrNoCapture
+	}
+	kind := CorrectionUndo
+	if s.captures[s.actions[len(s.actions)-1]].End != nil {
+		kind = CorrectionReopen
+	}
+	c := s.correction(kind)
+	s.actions = s.actions[:len(s.actions)-1]
+	s.captures = c.apply(s.captures)
+	s.corrections = append(s.corrections, c)
+	s.writeJournal(journalEntry{Type: entryCorrection, Time: c.Time, Correction: &c})
+	return c.Capture, nil
+}
+
+// Relabel changes the capture of the last key press of a running session to
+// the key, the label and the attributes of the binding of key and returns the
+// relabeled capture. The Hz and the time of the capture stay the same and an
+// interval stays an interval, which the key it got closes.
+func (s *Session) Relabel(key rune) (Capture, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.running {
+		return Capture{}, ErrNotRunning
+	}
+	if len(s.actions) == 0 {
+		return Capture{}, ErrNoCapture
+	}
+	b, ok := s.config.Binding(key)
+	if !ok {
+		return Capture{}, ErrUnknownKey
+	}
+	c := s.correction(CorrectionRelabel)
+	c.Key, c.Label, c.Attributes = key, b.Label, b.Attributes
+	s.captures = c.apply(s.captures)
+	s.corrections = append(s.corrections, c)
+	s.writeJournal(journalEntry{Type: entryCorrection, Time: c.Time, Correction: &c})
+	return s.captures[s.actions[len(s.actions)-1]], nil
+}
+
+func (s *Session) correction(kind CorrectionKind) Correction {
+	return Correction{
+		Kind:    kind,
+		Time:    s.clock.Now(),
+		Elapsed: s.elapsed(),
+		Capture: s.captures[s.actions[len(s.actions)-1]],
+	}
+}
+
+// Corrections returns the corrections made to the captures of the session in
+// the order they were made.
+func (s *Session) Corrections() []Correction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Correction(nil), s.corrections...)
+}
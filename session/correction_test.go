@@ -0,0 +1,296 @@
+package session
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var correctionKeys = []Binding{
+	{Key: "q", Label: "Visual memory", Attributes: map[string]string{"Modality": "Visual"}},
+	{Key: "w", Label: "Auditory memory", Attributes: map[string]string{"Modality": "Auditory"}},
+	{Key: "f", Label: "Floating", Interval: true},
+	{Key: "g", Label: "Focus", Interval: true},
+}
+
+// journaled starts a session with correctionKeys on a fake clock that is
+// journaled to a file in a temporary directory, and returns the session, the
+// clock and the path of the journal.
+func journaled(t *testing.T) (*Session, *fakeClock, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	j, err := CreateJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { j.Close() })
+	c := newFakeClock()
+	s := New(Config{TotalTime: 30 * time.Minute, StartHz: 14, EndHz: 8, Keys: correctionKeys}, c)
+	s.SetJournal(j)
+	s.Start()
+	return s, c, path
+}
+
+// press advances the clock by d and captures key.
+func press(t *testing.T, s *Session, c *fakeClock, d time.Duration, key rune) {
+	t.Helper()
+	c.advance(d)
+	if _, err := s.Capture(key); err != nil {
+		t.Fatalf("Capture(%q) err = %v", key, err)
+	}
+}
+
+// summary describes captures by their key and elapsed time, e.g. "q@1m0s".
+// An interval also shows its end, which is empty while it is open, and
+// whether it was closed at the end of the session, e.g. "f@1m0s-2m0s(auto)".
+func summary(captures []Capture) string {
+	var s []string
+	for _, c := range captures {
+		d := fmt.Sprintf("%c@%v", c.Key, c.Elapsed)
+		if c.Interval {
+			d += "-"
+		}
+		if c.End != nil {
+			d += c.End.Elapsed.String()
+			if c.End.Auto {
+				d += "(auto)"
+			}
+		}
+		s = append(s, d)
+	}
+	return strings.Join(s, " ")
+}
+
+func kinds(corrections []Correction) []CorrectionKind {
+	var k []CorrectionKind
+	for _, c := range corrections {
+		k = append(k, c.Kind)
+	}
+	return k
+}
+
+// checkReplay stops the session and checks that reading its journal gives
+// the captures, the pauses and the corrections of its record. A nil and an
+// empty list are taken as equal.
+func checkReplay(t *testing.T, s *Session, path string) {
+	t.Helper()
+	s.Stop(EndStopped)
+	if err := s.JournalErr(); err != nil {
+		t.Fatal(err)
+	}
+	want := s.Record()
+	got, err := ReadJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (len(got.Captures) != 0 || len(want.Captures) != 0) && !reflect.DeepEqual(got.Captures, want.Captures) {
+		t.Errorf("replayed captures = %+v, want %+v", got.Captures, want.Captures)
+	}
+	if (len(got.Pauses) != 0 || len(want.Pauses) != 0) && !reflect.DeepEqual(got.Pauses, want.Pauses) {
+		t.Errorf("replayed pauses = %+v, want %+v", got.Pauses, want.Pauses)
+	}
+	if (len(got.Corrections) != 0 || len(want.Corrections) != 0) && !reflect.DeepEqual(got.Corrections, want.Corrections) {
+		t.Errorf("replayed corrections = %+v, want %+v", got.Corrections, want.Corrections)
+	}
+}
+
+func TestCorrections(t *testing.T) {
+	tests := []struct {
+		name            string
+		run             func(t *testing.T, s *Session, c *fakeClock) error
+		wantErr         error
+		wantCaptures    string
+		wantCorrections []CorrectionKind
+	}{
+		{
+			name: "undo",
+			run: func(t *testing.T, s *Session, c *fakeClock) error {
+				press(t, s, c, time.Minute, 'q')
+				press(t, s, c, time.Minute, 'w')
+				_, err := s.Undo()
+				return err
+			},
+			wantCaptures:    "q@1m0s",
+			wantCorrections: []CorrectionKind{CorrectionUndo},
+		},
+		{
+			name: "undo twice",
+			run: func(t *testing.T, s *Session, c *fakeClock) error {
+				press(t, s, c, time.Minute, 'q')
+				press(t, s, c, time.Minute, 'w')
+				if _, err := s.Undo(); err != nil {
+					return err
+				}
+				_, err := s.Undo()
+				return err
+			},
+			wantCorrections: []CorrectionKind{CorrectionUndo, CorrectionUndo},
+		},
+		{
+			name: "undo without captures",
+			run: func(t *testing.T, s *Session, c *fakeClock) error {
+				_, err := s.Undo()
+				return err
+			},
+			wantErr: ErrNoCapture,
+		},
+		{
+			name: "undo more than captured",
+			run: func(t *testing.T, s *Session, c *fakeClock) error {
+				press(t, s, c, time.Minute, 'q')
+				if _, err := s.Undo(); err != nil {
+					return err
+				}
+				_, err := s.Undo()
+				return err
+			},
+			wantErr:         ErrNoCapture,
+			wantCorrections: []CorrectionKind{CorrectionUndo},
+		},
+		{
+			name: "undo the later of two captures of a key",
+			run: func(t *testing.T, s *Session, c *fakeClock) error {
+				press(t, s, c, time.Minute, 'q')
+				press(t, s, c, time.Minute, 'q')
+				_, err := s.Undo()
+				return err
+			},
+			wantCaptures:    "q@1m0s",
+			wantCorrections: []CorrectionKind{CorrectionUndo},
+		},
+		{
+			name: "relabel",
+			run: func(t *testing.T, s *Session, c *fakeClock) error {
+				press(t, s, c, time.Minute, 'q')
+				press(t, s, c, time.Minute, 'q')
+				_, err := s.Relabel('w')
+				return err
+			},
+			wantCaptures:    "q@1m0s w@2m0s",
+			wantCorrections: []CorrectionKind{CorrectionRelabel},
+		},
+		{
+			name: "relabel to an unbound key",
+			run: func(t *testing.T, s *Session, c *fakeClock) error {
+				press(t, s, c, time.Minute, 'q')
+				_, err := s.Relabel('x')
+				return err
+			},
+			wantErr:      ErrUnknownKey,
+			wantCaptures: "q@1m0s",
+		},
+		{
+			name: "relabel without captures",
+			run: func(t *testing.T, s *Session, c *fakeClock) error {
+				_, err := s.Relabel('w')
+				return err
+			},
+			wantErr: ErrNoCapture,
+		},
+		{
+			name: "relabel and undo",
+			run: func(t *testing.T, s *Session, c *fakeClock) error {
+				press(t, s, c, time.Minute, 'q')
+				press(t, s, c, time.Minute, 'q')
+				if _, err := s.Relabel('w'); err != nil {
+					return err
+				}
+				_, err := s.Undo()
+				return err
+			},
+			wantCaptures:    "q@1m0s",
+			wantCorrections: []CorrectionKind{CorrectionRelabel, CorrectionUndo},
+		},
+		{
+			name: "relabel after undo",
+			run: func(t *testing.T, s *Session, c *fakeClock) error {
+				press(t, s, c, time.Minute, 'q')
+				press(t, s, c, time.Minute, 'w')
+				if _, err := s.Undo(); err != nil {
+					return err
+				}
+				_, err := s.Relabel('w')
+				return err
+			},
+			wantCaptures:    "w@1m0s",
+			wantCorrections: []CorrectionKind{CorrectionUndo, CorrectionRelabel},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, c, path := journaled(t)
+			if err := tt.run(t, s, c); err != tt.wantErr {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got := summary(s.Captures()); got != tt.wantCaptures {
+				t.Errorf("captures = %q, want %q", got, tt.wantCaptures)
+			}
+			if got := kinds(s.Corrections()); !reflect.DeepEqual(got, tt.wantCorrections) {
+				t.Errorf("corrections = %v, want %v", got, tt.wantCorrections)
+			}
+			checkReplay(t, s, path)
+		})
+	}
+}
+
+func TestRelabelTakesBinding(t *testing.T) {
+	s, c, _ := journaled(t)
+	press(t, s, c, time.Minute, 'q')
+	got, err := s.Relabel('w')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Key != 'w' || got.Label != "Auditory memory" || got.Attributes["Modality"] != "Auditory" {
+		t.Errorf("Relabel('w') = %c %q %v, want the binding of 'w'", got.Key, got.Label, got.Attributes)
+	}
+	if got.Elapsed != time.Minute {
+		t.Errorf("Relabel('w').Elapsed = %v, want %v", got.Elapsed, time.Minute)
+	}
+	cs := s.Corrections()
+	if len(cs) != 1 || cs[0].Capture.Key != 'q' || cs[0].Capture.Label != "Visual memory" {
+		t.Errorf("Corrections() = %+v, want the capture as it was before", cs)
+	}
+}
+
+func TestCorrectionNotRunning(t *testing.T) {
+	c := newFakeClock()
+	s := New(Config{TotalTime: time.Minute, Keys: correctionKeys}, c)
+	if _, err := s.Undo(); err != ErrNotRunning {
+		t.Errorf("Undo before Start err = %v, want %v", err, ErrNotRunning)
+	}
+	s.Start()
+	press(t, s, c, time.Second, 'q')
+	s.Stop(EndStopped)
+	if _, err := s.Undo(); err != ErrNotRunning {
+		t.Errorf("Undo after Stop err = %v, want %v", err, ErrNotRunning)
+	}
+	if _, err := s.Relabel('w'); err != ErrNotRunning {
+		t.Errorf("Relabel after Stop err = %v, want %v", err, ErrNotRunning)
+	}
+}
+
+func TestCorrectedMatchesKeyAndElapsed(t *testing.T) {
+	captures := []Capture{
+		{Key: 'q', Elapsed: time.Minute},
+		{Key: 'w', Elapsed: time.Minute},
+		{Key: 'q', Elapsed: 2 * time.Minute},
+	}
+	tests := []struct {
+		c    Capture
+		want int
+	}{
+		{Capture{Key: 'q', Elapsed: time.Minute}, 0},
+		{Capture{Key: 'w', Elapsed: time.Minute}, 1},
+		{Capture{Key: 'q', Elapsed: 2 * time.Minute}, 2},
+		{Capture{Key: 'w', Elapsed: 2 * time.Minute}, -1},
+		{Capture{Key: 'x', Elapsed: time.Minute}, -1},
+	}
+	for _, tt := range tests {
+		if got := corrected(captures, tt.c); got != tt.want {
+			t.Errorf("corrected(%c@%v) = %d, want %d", tt.c.Key, tt.c.Elapsed, got, tt.want)
+		}
+	}
+}
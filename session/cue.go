@@ -0,0 +1,88 @@
+package session
+
+import (
+	"sort"
+	"time"
+)
+
+// CueKind is the kind of a cue.
+type CueKind string
+
+// The kinds of cues.
+const (
+	// CueCapturing is the start of key capturing, once the offset of the
+	// session or of a playlist part has passed.
+	CueCapturing CueKind = "capturing"
+	// CueInterval is rung every so many minutes.
+	CueInterval CueKind = "interval"
+	// CueBand is the crossing of a band boundary.
+	CueBand CueKind = "band"
+	// CueLastMinute is the start of the last minute of the session.
+	CueLastMinute CueKind = "last minute"
+)
+
+// Cues selects the points of a session that are announced with a cue, e.g.
+// the terminal bell, so that they can be followed with closed eyes.
+type Cues struct {
+	// Capturing cues the start of key capturing.
+	Capturing bool
+	// Every cues every so many minutes of the session, if it is not 0.
+	Every float64
+	// Bands cues the crossings of the band boundaries.
+	Bands bool
+	// LastMinute cues the start of the last minute.
+	LastMinute bool
+}
+
+// Cue is a point of a session that is announced. The Band of a band cue is
+// the band that is entered.
+type Cue struct {
+	Kind    CueKind
+	Elapsed time.Duration
+	Band    string `json:",omitempty"`
+}
+
+// Event returns what the cue announces, e.g. 'entering theta' or 'last
+// minute'.
+func (c Cue) Event() string {
+	if c.Kind == CueBand {
+		return "entering " + c.Band
+	}
+	return string(c.Kind)
+}
+
+// CuePoints returns the cues of a session that runs with the configuration,
+// sorted by the elapsed time at which they happen. Cues at the very start
+// of the session are left out as there is nothing to announce.
+func (c Config) CuePoints() []Cue {
+	var cues []Cue
+	if c.Cues.Capturing {
+		for i := 0; i < c.PartCount(); i++ {
+			start := c.PartStart(i) + c.PartConfig(i).Offset
+			cues = append(cues, Cue{Kind: CueCapturing, Elapsed: start})
+		}
+	}
+	if every := time.Duration(c.Cues.Every * float64(time.Minute)); every > 0 {
+		for e := every; e < c.TotalTime; e += every {
+			cues = append(cues, Cue{Kind: CueInterval, Elapsed: e})
+		}
+	}
+	if c.Cues.Bands {
+		for _, m := range c.Milestones() {
+			if m.Kind == MilestoneBand {
+				cues = append(cues, Cue{Kind: CueBand, Elapsed: m.Elapsed, Band: m.Band})
+			}
+		}
+	}
+	if c.Cues.LastMinute && c.TotalTime > time.Minute {
+		cues = append(cues, Cue{Kind: CueLastMinute, Elapsed: c.TotalTime - time.Minute})
+	}
+	var points []Cue
+	for _, cue := range cues {
+		if cue.Elapsed > 0 {
+			points = append(points, cue)
+		}
+	}
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Elapsed < points[j].Elapsed })
+	return points
+}
@@ -0,0 +1,117 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCuePoints(t *testing.T) {
+	cue := func(kind CueKind, elapsed time.Duration) Cue {
+		return Cue{Kind: kind, Elapsed: elapsed}
+	}
+	band := func(elapsed time.Duration, name string) Cue {
+		return Cue{Kind: CueBand, Elapsed: elapsed, Band: name}
+	}
+	descending := Config{TotalTime: 11 * time.Minute, Offset: time.Minute, StartHz: 14, EndHz: 2, Bands: DefaultBands}
+	with := func(c Config, cues Cues) Config {
+		c.Cues = cues
+		return c
+	}
+	tests := []struct {
+		name   string
+		config Config
+		want   []Cue
+	}{
+		{
+			name:   "none",
+			config: descending,
+		},
+		{
+			name:   "every",
+			config: Config{TotalTime: 10 * time.Minute, Cues: Cues{Every: 3}},
+			want:   []Cue{cue(CueInterval, 3*time.Minute), cue(CueInterval, 6*time.Minute), cue(CueInterval, 9*time.Minute)},
+		},
+		{
+			name:   "every up to the end",
+			config: Config{TotalTime: 9 * time.Minute, Cues: Cues{Every: 3}},
+			want:   []Cue{cue(CueInterval, 3*time.Minute), cue(CueInterval, 6*time.Minute)},
+		},
+		{
+			name:   "every half a minute",
+			config: Config{TotalTime: 2 * time.Minute, Cues: Cues{Every: 0.5}},
+			want:   []Cue{cue(CueInterval, 30*time.Second), cue(CueInterval, time.Minute), cue(CueInterval, 90*time.Second)},
+		},
+		{
+			name:   "every longer than the session",
+			config: Config{TotalTime: 2 * time.Minute, Cues: Cues{Every: 5}},
+		},
+		{
+			name:   "last minute",
+			config: Config{TotalTime: 10 * time.Minute, Cues: Cues{LastMinute: true}},
+			want:   []Cue{cue(CueLastMinute, 9*time.Minute)},
+		},
+		{
+			name:   "last minute of a one minute session",
+			config: Config{TotalTime: time.Minute, Cues: Cues{LastMinute: true}},
+		},
+		{
+			name:   "last minute of a shorter session",
+			config: Config{TotalTime: 30 * time.Second, Cues: Cues{LastMinute: true}},
+		},
+		{
+			name:   "capturing",
+			config: Config{TotalTime: 10 * time.Minute, Offset: 2 * time.Minute, Cues: Cues{Capturing: true}},
+			want:   []Cue{cue(CueCapturing, 2*time.Minute)},
+		},
+		{
+			name:   "capturing without offset",
+			config: Config{TotalTime: 10 * time.Minute, Cues: Cues{Capturing: true}},
+		},
+		{
+			name: "capturing in each part",
+			config: NewPlaylist("descent", []Part{
+				{Config: Config{TotalTime: 10 * time.Minute}},
+				{Gap: time.Minute, Config: Config{TotalTime: 5 * time.Minute, Offset: 2 * time.Minute}},
+				{Config: Config{TotalTime: 5 * time.Minute, Offset: 30 * time.Second}},
+			}, Config{Cues: Cues{Capturing: true}}),
+			want: []Cue{cue(CueCapturing, 13*time.Minute), cue(CueCapturing, 16*time.Minute+30*time.Second)},
+		},
+		{
+			name:   "bands",
+			config: with(descending, Cues{Bands: true}),
+			want: []Cue{
+				band(time.Minute+50*time.Second, "alpha"),
+				band(6*time.Minute, "theta"),
+				band(9*time.Minute+20*time.Second, "delta"),
+			},
+		},
+		{
+			name:   "all",
+			config: with(descending, Cues{Capturing: true, Every: 5, Bands: true, LastMinute: true}),
+			want: []Cue{
+				cue(CueCapturing, time.Minute),
+				band(time.Minute+50*time.Second, "alpha"),
+				cue(CueInterval, 5*time.Minute),
+				band(6*time.Minute, "theta"),
+				band(9*time.Minute+20*time.Second, "delta"),
+				cue(CueInterval, 10*time.Minute),
+				cue(CueLastMinute, 10*time.Minute),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.config.CuePoints()
+			if len(got) != len(tt.want) {
+				t.Fatalf("CuePoints() = %+v, want %+v", got, tt.want)
+			}
+			for i, w := range tt.want {
+				g := got[i]
+				// Band cues are found by bisection, see TestMilestones.
+				if d := g.Elapsed - w.Elapsed; g.Kind != w.Kind || g.Band != w.Band || d < -time.Microsecond || d > resolution {
+					t.Errorf("cue %d = %+v, want %+v", i, g, w)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package session
+
+import (
+	"fmt"
+	"math"
+)
+
+// Curve is the shape of the progression of the Hz during a ramp.
+type Curve string
+
+// The supported curves. Apart from the linear one, they all start and end at
+// the same Hz as a linear ramp but progress differently in between.
+const (
+	// CurveLinear progresses with a constant rate.
+	CurveLinear Curve = "Linear"
+	// CurveExponential starts slowly and speeds up towards the end.
+	CurveExponential Curve = "Exponential"
+	// CurveLogarithmic starts fast and slows down towards the end.
+	CurveLogarithmic Curve = "Logarithmic"
+	// CurveSigmoid is slow at both ends and fast in the middle, following
+	// the logistic function.
+	CurveSigmoid Curve = "Sigmoid"
+	// CurveCosine eases in and out following half a cosine period.
+	CurveCosine Curve = "Cosine"
+)
+
+// Curves holds all the supported curves in the order they are cycled.
+var Curves = []Curve{CurveLinear, CurveExponential, CurveLogarithmic, CurveSigmoid, CurveCosine}
+
+const (
+	expSteepness     = 3.0
+	sigmoidSteepness = 10.0
+)
+
+// Valid returns an error if the curve is not supported.
+func (c Curve) Valid() error {
+	for _, curve := range Curves {
+		if c == curve {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown curve %q", string(c))
+}
+
+// Next returns the curve that follows c in Curves.
+func (c Curve) Next() Curve {
+	for i, curve := range Curves {
+		if c == curve {
+			return Curves[(i+1)%len(Curves)]
+		}
+	}
+	return CurveLinear
+}
+
+// Progress maps the fraction x of a ramp's duration, between 0 and 1, to the
+// fraction of the ramp's Hz difference that has been covered. Unknown curves
+// are treated as linear.
+func (c Curve) Progress(x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	switch c {
+	case CurveExponential:
+		return math.Expm1(expSteepness*x) / math.Expm1(expSteepness)
+	case CurveLogarithmic:
+		return math.Log1p(math.Expm1(expSteepness)*x) / expSteepness
+	case CurveSigmoid:
+		logistic := func(x float64) float64 {
+			return 1 / (1 + math.Exp(-sigmoidSteepness*(x-0.5)))
+		}
+		return (logistic(x) - logistic(0)) / (logistic(1) - logistic(0))
+	case CurveCosine:
+		return (1 - math.Cos(math.Pi*x)) / 2
+	}
+	return x
+}
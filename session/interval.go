@@ -0,0 +1,100 @@
+package session
+
+import "time"
+
+// IntervalEnd is the end of an interval capture with the values of Hz and
+// base Hz that were recorded when it was closed.
+type IntervalEnd struct {
+	Time    time.Time // wall clock time of the end
+	Elapsed time.Duration
+	Hz      float64
+	BaseHz  float64
+	Band    string `json:",omitempty"`
+	Segment int
+	Part    int `json:",omitempty"`
+	// Auto is true when the interval was still open when the session ended
+	// and was closed then.
+	Auto bool `json:",omitempty"`
+}
+
+// Open reports whether the capture is an interval that has not been closed
+// yet.
+func (c Capture) Open() bool {
+	return c.Interval && c.End == nil
+}
+
+// Duration returns how long an interval capture lasted. It is 0 for a point
+// capture and for an interval that is still open.
+func (c Capture) Duration() time.Duration {
+	if c.End == nil {
+		return 0
+	}
+	return c.End.Elapsed - c.Elapsed
+}
+
+// intervalEnd returns the end of an interval closed at a certain elapsed time
+// of a session that runs with the configuration.
+func (c Config) intervalEnd(t time.Time, elapsed time.Duration, auto bool) *IntervalEnd {
+	hz, seg := c.Hz(elapsed)
+	part, _ := c.PartAt(elapsed)
+	return &IntervalEnd{
+		Time:    t,
+		Elapsed: elapsed,
+		Hz:      hz,
+		BaseHz:  c.BaseHz(elapsed),
+		Band:    c.Bands.Classify(hz),
+		Segment: seg,
+		Part:    part,
+		Auto:    auto,
+	}
+}
+
+// openInterval returns the index of the open interval capture of key, or -1
+// if there is none.
+func openInterval(captures []Capture, key rune) int {
+	for i := len(captures) - 1; i >= 0; i-- {
+		if captures[i].Open() && captures[i].Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// closeInterval replaces the open interval that c is the closed version of.
+// It is used when replaying a journal.
+func closeInterval(captures []Capture, c Capture) {
+	for i := len(captures) - 1; i >= 0; i-- {
+		if captures[i].Open() && captures[i].Key == c.Key && captures[i].Elapsed == c.Elapsed {
+			captures[i] = c
+			return
+		}
+	}
+}
+
+// closeIntervals closes the intervals that are still open at a certain time
+// of the session, marking them as closed automatically, and returns the
+// closed captures.
+func (c Config) closeIntervals(captures []Capture, t time.Time, elapsed time.Duration) []Capture {
+	var closed []Capture
+	for i := range captures {
+		if captures[i].Open() {
+			captures[i].End = c.intervalEnd(t, elapsed, true)
+			closed = append(closed, captures[i])
+		}
+	}
+	return closed
+}
+
+// OpenIntervals returns the interval captures of the session that have not
+// been closed yet.
+func (s *Session) OpenIntervals() []Capture {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var open []Capture
+	for _, c := range s.captures {
+		if c.Open() {
+			open = append(open, c)
+		}
+	}
+	return open
+}
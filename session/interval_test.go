@@ -0,0 +1,266 @@
+package session
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIntervals(t *testing.T) {
+	tests := []struct {
+		name            string
+		run             func(t *testing.T, s *Session, c *fakeClock)
+		wantCaptures    string
+		wantOpen        string
+		wantCorrections []CorrectionKind
+	}{
+		{
+			name:         "open",
+			run:          func(t *testing.T, s *Session, c *fakeClock) { press(t, s, c, time.Minute, 'f') },
+			wantCaptures: "f@1m0s-",
+			wantOpen:     "f@1m0s-",
+		},
+		{
+			name: "close",
+			run: func(t *testing.T, s *Session, c *fakeClock) {
+				press(t, s, c, time.Minute, 'f')
+				press(t, s, c, 2*time.Minute, 'f')
+			},
+			wantCaptures: "f@1m0s-3m0s",
+		},
+		{
+			name: "open again after close",
+			run: func(t *testing.T, s *Session, c *fakeClock) {
+				press(t, s, c, time.Minute, 'f')
+				press(t, s, c, time.Minute, 'f')
+				press(t, s, c, time.Minute, 'f')
+			},
+			wantCaptures: "f@1m0s-2m0s f@3m0s-",
+			wantOpen:     "f@3m0s-",
+		},
+		{
+			name: "overlapping intervals and a point capture",
+			run: func(t *testing.T, s *Session, c *fakeClock) {
+				press(t, s, c, time.Minute, 'f')
+				press(t, s, c, time.Minute, 'g')
+				press(t, s, c, time.Minute, 'q')
+				press(t, s, c, time.Minute, 'f')
+			},
+			wantCaptures: "f@1m0s-4m0s g@2m0s- q@3m0s",
+			wantOpen:     "g@2m0s-",
+		},
+		{
+			name: "pause while open",
+			run: func(t *testing.T, s *Session, c *fakeClock) {
+				press(t, s, c, time.Minute, 'f')
+				c.advance(time.Minute)
+				s.Pause()
+				c.advance(10 * time.Minute)
+				s.Resume()
+				press(t, s, c, time.Minute, 'f')
+			},
+			wantCaptures: "f@1m0s-3m0s",
+		},
+		{
+			name: "undo a close reopens",
+			run: func(t *testing.T, s *Session, c *fakeClock) {
+				press(t, s, c, time.Minute, 'f')
+				press(t, s, c, time.Minute, 'q')
+				press(t, s, c, time.Minute, 'f')
+				undo(t, s, 'f')
+			},
+			wantCaptures:    "f@1m0s- q@2m0s",
+			wantOpen:        "f@1m0s-",
+			wantCorrections: []CorrectionKind{CorrectionReopen},
+		},
+		{
+			name: "undo a reopened interval removes it",
+			run: func(t *testing.T, s *Session, c *fakeClock) {
+				press(t, s, c, time.Minute, 'f')
+				press(t, s, c, time.Minute, 'q')
+				press(t, s, c, time.Minute, 'f')
+				undo(t, s, 'f')
+				undo(t, s, 'q')
+				undo(t, s, 'f')
+			},
+			wantCorrections: []CorrectionKind{CorrectionReopen, CorrectionUndo, CorrectionUndo},
+		},
+		{
+			name: "undo a point capture after a close",
+			run: func(t *testing.T, s *Session, c *fakeClock) {
+				press(t, s, c, time.Minute, 'f')
+				press(t, s, c, time.Minute, 'f')
+				press(t, s, c, time.Minute, 'q')
+				undo(t, s, 'q')
+			},
+			wantCaptures:    "f@1m0s-2m0s",
+			wantCorrections: []CorrectionKind{CorrectionUndo},
+		},
+		{
+			name: "undo the opening of an interval",
+			run: func(t *testing.T, s *Session, c *fakeClock) {
+				press(t, s, c, time.Minute, 'f')
+				press(t, s, c, time.Minute, 'g')
+				undo(t, s, 'g')
+			},
+			wantCaptures:    "f@1m0s-",
+			wantOpen:        "f@1m0s-",
+			wantCorrections: []CorrectionKind{CorrectionUndo},
+		},
+		{
+			name: "relabel a closed interval",
+			run: func(t *testing.T, s *Session, c *fakeClock) {
+				press(t, s, c, time.Minute, 'f')
+				press(t, s, c, time.Minute, 'f')
+				relabel(t, s, 'g')
+				// The relabeled interval is closed, so its new key
+				// opens another one.
+				press(t, s, c, time.Minute, 'g')
+			},
+			wantCaptures:    "g@1m0s-2m0s g@3m0s-",
+			wantOpen:        "g@3m0s-",
+			wantCorrections: []CorrectionKind{CorrectionRelabel},
+		},
+		{
+			name: "relabel an open interval",
+			run: func(t *testing.T, s *Session, c *fakeClock) {
+				press(t, s, c, time.Minute, 'f')
+				relabel(t, s, 'g')
+				// The new key closes it, the old one opens another.
+				press(t, s, c, time.Minute, 'f')
+				press(t, s, c, time.Minute, 'g')
+			},
+			wantCaptures:    "g@1m0s-3m0s f@2m0s-",
+			wantOpen:        "f@2m0s-",
+			wantCorrections: []CorrectionKind{CorrectionRelabel},
+		},
+		{
+			name: "undo after relabeling a close",
+			run: func(t *testing.T, s *Session, c *fakeClock) {
+				press(t, s, c, time.Minute, 'f')
+				press(t, s, c, time.Minute, 'f')
+				relabel(t, s, 'g')
+				undo(t, s, 'g')
+			},
+			wantCaptures:    "g@1m0s-",
+			wantOpen:        "g@1m0s-",
+			wantCorrections: []CorrectionKind{CorrectionRelabel, CorrectionReopen},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, c, path := journaled(t)
+			tt.run(t, s, c)
+			if got := summary(s.Captures()); got != tt.wantCaptures {
+				t.Errorf("captures = %q, want %q", got, tt.wantCaptures)
+			}
+			if got := summary(s.OpenIntervals()); got != tt.wantOpen {
+				t.Errorf("open intervals = %q, want %q", got, tt.wantOpen)
+			}
+			if got := kinds(s.Corrections()); !reflect.DeepEqual(got, tt.wantCorrections) {
+				t.Errorf("corrections = %v, want %v", got, tt.wantCorrections)
+			}
+			checkReplay(t, s, path)
+		})
+	}
+}
+
+// undo undoes the last key press of s, which must have been of key.
+func undo(t *testing.T, s *Session, key rune) {
+	t.Helper()
+	c, err := s.Undo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Key != key {
+		t.Fatalf("Undo() = %c, want %c", c.Key, key)
+	}
+}
+
+func relabel(t *testing.T, s *Session, key rune) {
+	t.Helper()
+	if _, err := s.Relabel(key); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStopClosesOpenIntervals(t *testing.T) {
+	tests := []struct {
+		name         string
+		run          func(t *testing.T, s *Session, c *fakeClock)
+		wantCaptures string
+	}{
+		{
+			name: "running",
+			run: func(t *testing.T, s *Session, c *fakeClock) {
+				press(t, s, c, time.Minute, 'f')
+				press(t, s, c, time.Minute, 'g')
+				press(t, s, c, time.Minute, 'g')
+				c.advance(time.Minute)
+			},
+			wantCaptures: "f@1m0s-4m0s(auto) g@2m0s-3m0s",
+		},
+		{
+			name: "paused",
+			run: func(t *testing.T, s *Session, c *fakeClock) {
+				press(t, s, c, time.Minute, 'f')
+				c.advance(time.Minute)
+				s.Pause()
+				c.advance(10 * time.Minute)
+			},
+			wantCaptures: "f@1m0s-2m0s(auto)",
+		},
+		{
+			name: "past the total time",
+			run: func(t *testing.T, s *Session, c *fakeClock) {
+				press(t, s, c, time.Minute, 'f')
+				c.advance(time.Hour)
+			},
+			wantCaptures: "f@1m0s-30m0s(auto)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, c, path := journaled(t)
+			tt.run(t, s, c)
+			s.Stop(EndStopped)
+			if got := summary(s.Captures()); got != tt.wantCaptures {
+				t.Errorf("captures = %q, want %q", got, tt.wantCaptures)
+			}
+			if open := s.OpenIntervals(); len(open) != 0 {
+				t.Errorf("OpenIntervals() = %v after Stop, want none", open)
+			}
+			checkReplay(t, s, path)
+		})
+	}
+}
+
+// TestReadJournalClosesOpenIntervals checks that the intervals left open by
+// a crash are closed at the last event of the journal.
+func TestReadJournalClosesOpenIntervals(t *testing.T) {
+	s, c, path := journaled(t)
+	press(t, s, c, time.Minute, 'f')
+	press(t, s, c, time.Minute, 'g')
+	press(t, s, c, time.Minute, 'g')
+	press(t, s, c, time.Minute, 'q')
+	c.advance(time.Minute)
+	r, err := ReadJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := summary(r.Captures), "f@1m0s-4m0s(auto) g@2m0s-3m0s q@4m0s"; got != want {
+		t.Errorf("captures = %q, want %q", got, want)
+	}
+}
+
+func TestIntervalDuration(t *testing.T) {
+	open := Capture{Interval: true, Elapsed: time.Minute}
+	closed := Capture{Interval: true, Elapsed: time.Minute, End: &IntervalEnd{Elapsed: 3 * time.Minute}}
+	point := Capture{Elapsed: time.Minute}
+	if !open.Open() || closed.Open() || point.Open() {
+		t.Errorf("Open() = %v, %v, %v, want true, false, false", open.Open(), closed.Open(), point.Open())
+	}
+	if open.Duration() != 0 || closed.Duration() != 2*time.Minute || point.Duration() != 0 {
+		t.Errorf("Duration() = %v, %v, %v, want 0, 2m, 0", open.Duration(), closed.Duration(), point.Duration())
+	}
+}
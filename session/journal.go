@@ -0,0 +1,162 @@
+package session
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrEmptyJournal is returned when reading a journal that does not contain
+// the start of a session.
+var ErrEmptyJournal = errors.New("journal does not contain a session")
+
+type entryType string
+
+const (
+	entryStart   entryType = "start"
+	entryCapture entryType = "capture"
+	// entryPause is the start of a pause and entryResume its end, which
+	// carries its duration.
+	entryPause  entryType = "pause"
+	entryResume entryType = "resume"
+	entryStop   entryType = "stop"
+	// entryCorrection is an undone, relabeled or reopened capture.
+	entryCorrection entryType = "correction"
+	// entryClose is the closing of an interval capture, which carries its
+	// end.
+	entryClose entryType = "close"
+)
+
+// journalEntry is a single line of the journal.
+type journalEntry struct {
+	Type    entryType
+	Time    time.Time
+	Config  *Config   `json:",omitempty"`
+	Capture *Capture  `json:",omitempty"`
+	Pause   *Pause    `json:",omitempty"`
+	Reason  EndReason `json:",omitempty"`
+	// Correction is applied to the captures read before it.
+	Correction *Correction `json:",omitempty"`
+}
+
+// Journal is a write-ahead log of a session. Each event of the session is
+// appended to the journal file as a line of JSON and synced to disk, so that
+// the session can be recovered after a crash. The journal should be removed
+// once the session has been logged properly.
+type Journal struct {
+	f *os.File
+}
+
+// CreateJournal creates a journal file at path, truncating it if it already
+// exists.
+func CreateJournal(path string) (*Journal, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &Journal{f: f}, nil
+}
+
+func (j *Journal) write(e journalEntry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := j.f.Write(append(b, '\n')); err != nil {
+		return err
+	}
+	return j.f.Sync()
+}
+
+// Close closes the journal file.
+func (j *Journal) Close() error {
+	return j.f.Close()
+}
+
+// Remove closes and removes the journal file.
+func (j *Journal) Remove() error {
+	j.f.Close()
+	return os.Remove(j.f.Name())
+}
+
+// ReadJournal reads the journal file at path and returns the record of the
+// session it contains, marked as recovered. A partially written last line,
+// as left by a crash, is ignored. If the journal does not contain a stop
+// event, the session is considered stopped at the time of its last event and
+// the intervals that are still open are closed then. A pause that was not
+// resumed is considered to have lasted until then.
+func ReadJournal(path string) (Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Record{}, err
+	}
+	defer f.Close()
+	r := Record{Recovered: true}
+	started := false
+	// paused is the pause that has started but not ended yet.
+	var paused *Pause
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		var e journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			// Only the last line is expected to be broken.
+			if scanner.Scan() {
+				return Record{}, fmt.Errorf("journal line %d: %v", line, err)
+			}
+			break
+		}
+		switch e.Type {
+		case entryStart:
+			if e.Config != nil {
+				r.Config = *e.Config
+			}
+			r.Started = e.Time
+			// Milestones and cues are not journaled as they are known
+			// from the configuration.
+			r.Milestones = r.Config.Milestones()
+			r.Cues = r.Config.CuePoints()
+			started = true
+		case entryCapture:
+			if e.Capture != nil {
+				r.Captures = append(r.Captures, *e.Capture)
+			}
+		case entryPause:
+			if e.Pause != nil {
+				p := *e.Pause
+				paused = &p
+			}
+		case entryResume:
+			if e.Pause != nil {
+				r.Pauses = append(r.Pauses, *e.Pause)
+				paused = nil
+			}
+		case entryCorrection:
+			if e.Correction != nil {
+				r.Captures = e.Correction.apply(r.Captures)
+				r.Corrections = append(r.Corrections, *e.Correction)
+			}
+		case entryClose:
+			if e.Capture != nil {
+				closeInterval(r.Captures, *e.Capture)
+			}
+		case entryStop:
+			r.EndReason = e.Reason
+		}
+		r.Stopped = e.Time
+	}
+	if err := scanner.Err(); err != nil {
+		return Record{}, err
+	}
+	if !started {
+		return Record{}, ErrEmptyJournal
+	}
+	if paused != nil {
+		paused.Duration = r.Stopped.Sub(paused.Start)
+		r.Pauses = append(r.Pauses, *paused)
+	}
+	r.Config.closeIntervals(r.Captures, r.Stopped, r.Elapsed())
+	return r, nil
+}
@@ -0,0 +1,191 @@
+package session
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeJournal runs a session with two captures and a pause on a fake clock,
+// journaling it to a file in dir without stopping it, and returns the path
+// of the journal and the clock.
+func writeJournal(t *testing.T, dir string) (string, *fakeClock) {
+	t.Helper()
+	path := filepath.Join(dir, "journal.jsonl")
+	j, err := CreateJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	c := newFakeClock()
+	s := New(Config{TotalTime: 30 * time.Minute, StartHz: 14, EndHz: 8, Keys: []Binding{{Key: "q", Label: "Q"}}}, c)
+	s.SetJournal(j)
+	s.Start()
+	c.advance(time.Minute)
+	if _, err := s.Capture('q'); err != nil {
+		t.Fatal(err)
+	}
+	s.Pause()
+	c.advance(time.Minute)
+	s.Resume()
+	c.advance(time.Minute)
+	if _, err := s.Capture('q'); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.JournalErr(); err != nil {
+		t.Fatal(err)
+	}
+	return path, c
+}
+
+func TestReadJournal(t *testing.T) {
+	tests := []struct {
+		name         string
+		corrupt      func(b []byte) []byte
+		wantErr      bool
+		wantCaptures int
+	}{
+		{
+			name:         "complete",
+			corrupt:      func(b []byte) []byte { return b },
+			wantCaptures: 2,
+		},
+		{
+			name:         "truncated last line",
+			corrupt:      func(b []byte) []byte { return append(b, `{"Type":"capture","Ti`...) },
+			wantCaptures: 2,
+		},
+		{
+			name:         "last line cut short",
+			corrupt:      func(b []byte) []byte { return b[:len(b)-10] },
+			wantCaptures: 1,
+		},
+		{
+			name: "broken line in the middle",
+			corrupt: func(b []byte) []byte {
+				return append([]byte(`{"Type":"sta`+"\n"), b...)
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, _ := writeJournal(t, t.TempDir())
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(path, tt.corrupt(b), 0644); err != nil {
+				t.Fatal(err)
+			}
+			r, err := ReadJournal(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("ReadJournal() err = nil, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadJournal() err = %v", err)
+			}
+			if !r.Recovered {
+				t.Error("Recovered = false, want true")
+			}
+			if len(r.Captures) != tt.wantCaptures {
+				t.Errorf("got %d captures, want %d", len(r.Captures), tt.wantCaptures)
+			}
+			if len(r.Pauses) != 1 || r.Pauses[0].Duration != time.Minute {
+				t.Errorf("Pauses = %+v, want one of %v", r.Pauses, time.Minute)
+			}
+		})
+	}
+}
+
+func TestReadJournalStoppedAtLastEvent(t *testing.T) {
+	path, c := writeJournal(t, t.TempDir())
+	r, err := ReadJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Stopped.Equal(c.Now()) {
+		t.Errorf("Stopped = %v, want the time of the last capture %v", r.Stopped, c.Now())
+	}
+	if got, want := r.Elapsed(), 2*time.Minute; got != want {
+		t.Errorf("Elapsed() = %v, want %v", got, want)
+	}
+	if r.EndReason != "" {
+		t.Errorf("EndReason = %q, want none", r.EndReason)
+	}
+}
+
+func TestReadJournalEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadJournal(path); err != ErrEmptyJournal {
+		t.Errorf("ReadJournal() err = %v, want %v", err, ErrEmptyJournal)
+	}
+}
+
+// TestReadJournalPaused checks that the time a session was paused before a
+// crash is not counted as running.
+func TestReadJournalPaused(t *testing.T) {
+	tests := []struct {
+		name string
+		// undo is undoing the capture while paused, after a minute.
+		undo        bool
+		wantStopped time.Duration
+		wantPause   time.Duration
+	}{
+		{name: "nothing while paused", wantStopped: time.Minute},
+		{name: "undo while paused", undo: true, wantStopped: 2 * time.Minute, wantPause: time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "journal.jsonl")
+			j, err := CreateJournal(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer j.Close()
+			c := newFakeClock()
+			s := New(Config{TotalTime: 30 * time.Minute, StartHz: 14, EndHz: 8, Keys: []Binding{{Key: "q", Label: "Q"}}}, c)
+			s.SetJournal(j)
+			s.Start()
+			started := c.Now()
+			c.advance(time.Minute)
+			if _, err := s.Capture('q'); err != nil {
+				t.Fatal(err)
+			}
+			s.Pause()
+			c.advance(time.Minute)
+			if tt.undo {
+				if _, err := s.Undo(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// The program crashes long after the last event.
+			c.advance(10 * time.Minute)
+			if err := s.JournalErr(); err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := ReadJournal(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := started.Add(tt.wantStopped); !r.Stopped.Equal(want) {
+				t.Errorf("Stopped = %v, want %v", r.Stopped, want)
+			}
+			want := Pause{Elapsed: time.Minute, Start: started.Add(time.Minute), Duration: tt.wantPause}
+			if len(r.Pauses) != 1 || r.Pauses[0] != want {
+				t.Errorf("Pauses = %+v, want %+v", r.Pauses, []Pause{want})
+			}
+			if got := r.Elapsed(); got != time.Minute {
+				t.Errorf("Elapsed() = %v, want %v", got, time.Minute)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package session
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// MilestoneKind is the kind of a milestone.
+type MilestoneKind string
+
+// The kinds of milestones.
+const (
+	// MilestoneBand is the crossing of a band boundary.
+	MilestoneBand MilestoneKind = "band"
+	// MilestoneTarget is the reaching of a target Hz.
+	MilestoneTarget MilestoneKind = "target"
+)
+
+// Milestone is a point of a session at which the Hz cross the boundary of a
+// band or reach a target Hz. The Hz of a band milestone are the boundary
+// that is crossed, or the Hz jumped to when a program segment does not
+// start where the previous one ended.
+type Milestone struct {
+	Kind    MilestoneKind
+	Elapsed time.Duration
+	Hz      float64
+	// Band is the band that is entered at a band milestone.
+	Band string `json:",omitempty"`
+}
+
+// Event returns what happens at the milestone, e.g. 'Entering theta' or
+// 'Reaching 10.00hz'.
+func (m Milestone) Event(c Config) string {
+	if m.Kind == MilestoneBand {
+		return "Entering " + m.Band
+	}
+	return fmt.Sprintf("Reaching %vhz", c.FormatHz(m.Hz))
+}
+
+// resolution is the precision with which the time of a milestone is found.
+const resolution = time.Millisecond
+
+// Milestones returns the milestones of a session that runs with the
+// configuration, sorted by the elapsed time at which they happen. As the
+// Hz of each program segment progress monotonically, the time of each
+// crossing is found by bisection. The milestones of a playlist session are
+// those of its parts.
+func (c Config) Milestones() []Milestone {
+	var ms []Milestone
+	if len(c.Parts) != 0 {
+		for i := range c.Parts {
+			start := c.PartStart(i)
+			pc := c.PartConfig(i)
+			// Like a program segment, a part that does not start in the
+			// band the previous one ended in jumps into the band of its
+			// start.
+			if i > 0 {
+				from := pc.Segments().StartHz()
+				band := c.Bands.Classify(from)
+				if band != "" && band != c.Bands.Classify(c.PartConfig(i-1).Segments().EndHz()) {
+					ms = append(ms, Milestone{Kind: MilestoneBand, Elapsed: start, Hz: from, Band: band})
+				}
+			}
+			for _, m := range pc.Milestones() {
+				m.Elapsed += start
+				ms = append(ms, m)
+			}
+		}
+		return ms
+	}
+	program := c.Segments()
+	start := c.Offset
+	prev := program.StartHz()
+	for _, seg := range program {
+		// A segment that does not start where the previous one ended
+		// jumps straight into the band of its start.
+		band := c.Bands.Classify(seg.FromHz)
+		if band != "" && band != c.Bands.Classify(prev) {
+			ms = append(ms, Milestone{Kind: MilestoneBand, Elapsed: start, Hz: seg.FromHz, Band: band})
+		}
+		at := start
+		seg := seg
+		ms = append(ms, c.crossings(seg.FromHz, seg.ToHz, func(hz float64) time.Duration {
+			return at + seg.solve(hz, c.Curve)
+		})...)
+		start += seg.Duration()
+		prev = seg.ToHz
+	}
+	var reached []Milestone
+	for _, m := range ms {
+		if m.Elapsed <= c.TotalTime {
+			reached = append(reached, m)
+		}
+	}
+	sort.SliceStable(reached, func(i, j int) bool { return reached[i].Elapsed < reached[j].Elapsed })
+	return reached
+}
+
+// crossings returns the milestones of a monotonic progression from Hz from
+// to Hz to, with at returning the elapsed time at which some Hz are reached.
+// A band is entered when the Hz reach its start going up or drop below it
+// going down, while a target is reached when the Hz become equal to it.
+func (c Config) crossings(from, to float64, at func(hz float64) time.Duration) []Milestone {
+	var ms []Milestone
+	for i, b := range c.Bands {
+		switch {
+		case from < b.FromHz && b.FromHz <= to:
+			ms = append(ms, Milestone{Kind: MilestoneBand, Elapsed: at(b.FromHz), Hz: b.FromHz, Band: b.Name})
+		case to < b.FromHz && b.FromHz <= from && i > 0:
+			ms = append(ms, Milestone{Kind: MilestoneBand, Elapsed: at(b.FromHz), Hz: b.FromHz, Band: c.Bands[i-1].Name})
+		}
+	}
+	for _, t := range c.Targets {
+		if (from < t && t <= to) || (to <= t && t < from) {
+			ms = append(ms, Milestone{Kind: MilestoneTarget, Elapsed: at(t), Hz: t})
+		}
+	}
+	return ms
+}
+
+// solve returns the time since the start of the segment at which its Hz
+// reach hz.
+func (s Segment) solve(hz float64, curve Curve) time.Duration {
+	lo, hi := time.Duration(0), s.Duration()
+	rising := s.ToHz > s.FromHz
+	for hi-lo > resolution {
+		mid := lo + (hi-lo)/2
+		if v := s.Hz(mid, curve); (v < hz) == rising {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return hi
+}
@@ -0,0 +1,158 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMilestones(t *testing.T) {
+	band := func(elapsed time.Duration, hz float64, name string) Milestone {
+		return Milestone{Kind: MilestoneBand, Elapsed: elapsed, Hz: hz, Band: name}
+	}
+	target := func(elapsed time.Duration, hz float64) Milestone {
+		return Milestone{Kind: MilestoneTarget, Elapsed: elapsed, Hz: hz}
+	}
+	tests := []struct {
+		name   string
+		config Config
+		want   []Milestone
+	}{
+		{
+			name:   "descending",
+			config: Config{TotalTime: 11 * time.Minute, Offset: time.Minute, StartHz: 14, EndHz: 2, Bands: DefaultBands},
+			want: []Milestone{
+				band(time.Minute+50*time.Second, 13, "alpha"),
+				band(6*time.Minute, 8, "theta"),
+				band(9*time.Minute+20*time.Second, 4, "delta"),
+			},
+		},
+		{
+			name:   "ascending",
+			config: Config{TotalTime: 10 * time.Minute, StartHz: 2, EndHz: 14, Bands: DefaultBands},
+			want: []Milestone{
+				band(time.Minute+40*time.Second, 4, "theta"),
+				band(5*time.Minute, 8, "alpha"),
+				band(9*time.Minute+10*time.Second, 13, "beta"),
+			},
+		},
+		{
+			name:   "descending from a boundary",
+			config: Config{TotalTime: 10 * time.Minute, StartHz: 8, EndHz: 4, Bands: DefaultBands},
+			want:   []Milestone{band(0, 8, "theta")},
+		},
+		{
+			name:   "ascending to a boundary",
+			config: Config{TotalTime: 10 * time.Minute, StartHz: 2, EndHz: 4, Bands: DefaultBands},
+			want:   []Milestone{band(10*time.Minute, 4, "theta")},
+		},
+		{
+			name:   "holding",
+			config: Config{TotalTime: 10 * time.Minute, StartHz: 10, EndHz: 10, Bands: DefaultBands, Targets: []float64{10}},
+		},
+		{
+			name:   "targets",
+			config: Config{TotalTime: 10 * time.Minute, StartHz: 14, EndHz: 8, Targets: []float64{14, 12, 10, 8, 20}},
+			want: []Milestone{
+				target(3*time.Minute+20*time.Second, 12),
+				target(6*time.Minute+40*time.Second, 10),
+				target(10*time.Minute, 8),
+			},
+		},
+		{
+			name: "targets and bands",
+			config: Config{TotalTime: 10 * time.Minute, StartHz: 14, EndHz: 8, Bands: DefaultBands,
+				Targets: []float64{12.5}},
+			want: []Milestone{
+				band(time.Minute+40*time.Second, 13, "alpha"),
+				target(2*time.Minute+30*time.Second, 12.5),
+			},
+		},
+		{
+			name:   "exponential",
+			config: Config{TotalTime: 10 * time.Minute, Curve: CurveExponential, StartHz: 14, EndHz: 4, Bands: DefaultBands},
+			want: []Milestone{
+				// At x of the ramp the Hz have dropped by
+				// 10 * expm1(3x) / expm1(3), by 1 at x = 0.3559 and
+				// by 6 at x = 0.8406.
+				band(3*time.Minute+33531188936, 13, "alpha"),
+				band(8*time.Minute+24365362874, 8, "theta"),
+			},
+		},
+		{
+			name:   "cosine",
+			config: Config{TotalTime: 10 * time.Minute, Curve: CurveCosine, StartHz: 14, EndHz: 4, Targets: []float64{9}},
+			want:   []Milestone{target(5*time.Minute, 9)},
+		},
+		{
+			name: "program jumping between segments",
+			config: Config{
+				TotalTime: 11 * time.Minute,
+				Offset:    time.Minute,
+				Bands:     DefaultBands,
+				Program: Program{
+					{Minutes: 5, FromHz: 14, ToHz: 14},
+					{Minutes: 5, FromHz: 10, ToHz: 6},
+				},
+			},
+			want: []Milestone{
+				band(6*time.Minute, 10, "alpha"),
+				band(8*time.Minute+30*time.Second, 8, "theta"),
+			},
+		},
+		{
+			name: "program continuing between segments",
+			config: Config{
+				TotalTime: 10 * time.Minute,
+				Bands:     DefaultBands,
+				Program: Program{
+					{Minutes: 5, FromHz: 12, ToHz: 10},
+					{Minutes: 5, FromHz: 10, ToHz: 6},
+				},
+			},
+			want: []Milestone{band(7*time.Minute+30*time.Second, 8, "theta")},
+		},
+		{
+			name: "program past the total time",
+			config: Config{
+				TotalTime: 5 * time.Minute,
+				Bands:     DefaultBands,
+				Program:   Program{{Minutes: 10, FromHz: 14, ToHz: 4}},
+			},
+			want: []Milestone{band(time.Minute, 13, "alpha")},
+		},
+		{
+			name: "playlist",
+			config: NewPlaylist("descent", []Part{
+				{Config: Config{TotalTime: 10 * time.Minute, StartHz: 14, EndHz: 9}},
+				{Gap: 2 * time.Minute, Config: Config{TotalTime: 5 * time.Minute, Offset: time.Minute, StartHz: 6, EndHz: 2}},
+				{Config: Config{TotalTime: 2 * time.Minute, StartHz: 2, EndHz: 2, Targets: []float64{2}}},
+			}, Config{Bands: DefaultBands}),
+			want: []Milestone{
+				band(2*time.Minute, 13, "alpha"),
+				// The second part jumps into theta as soon as its gap
+				// ends, and the third continues where it ended.
+				band(12*time.Minute, 6, "theta"),
+				band(15*time.Minute, 4, "delta"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.config.Milestones()
+			if len(got) != len(tt.want) {
+				t.Fatalf("Milestones() = %+v, want %+v", got, tt.want)
+			}
+			for i, w := range tt.want {
+				g := got[i]
+				if g.Kind != w.Kind || g.Hz != w.Hz || g.Band != w.Band {
+					t.Errorf("milestone %d = %+v, want %+v", i, g, w)
+				}
+				// The time is found by bisection, within the
+				// resolution after the exact crossing.
+				if d := g.Elapsed - w.Elapsed; d < -time.Microsecond || d > resolution {
+					t.Errorf("milestone %d at %v, want %v", i, g.Elapsed, w.Elapsed)
+				}
+			}
+		})
+	}
+}
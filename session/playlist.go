@@ -0,0 +1,94 @@
+package session
+
+import "time"
+
+// Part is a part of a playlist session. It runs with a configuration of its
+// own, usually that of a preset, after an optional gap during which nothing
+// is captured. The offset and the total time of the part are counted from
+// the end of its gap.
+type Part struct {
+	Gap    time.Duration
+	Config Config
+}
+
+// NewPlaylist returns the configuration of a session that runs the parts of
+// a playlist named name one after the other. The precision, the keys, the
+// dimensions, the bands and the cues of shared apply to the whole session.
+// The total time of the session is the sum of the gaps and the total times
+// of the parts.
+func NewPlaylist(name string, parts []Part, shared Config) Config {
+	c := Config{
+		Playlist:   name,
+		Precision:  shared.Precision,
+		Keys:       shared.Keys,
+		Dimensions: shared.Dimensions,
+		Bands:      shared.Bands,
+		Cues:       shared.Cues,
+	}
+	for _, p := range parts {
+		p.Config.Keys, p.Config.Dimensions, p.Config.Bands = nil, nil, nil
+		p.Config.Precision = 0
+		c.Parts = append(c.Parts, p)
+		c.TotalTime += p.Gap + p.Config.TotalTime
+	}
+	if len(parts) != 0 {
+		c.Offset = parts[0].Gap + parts[0].Config.Offset
+	}
+	return c
+}
+
+// PartCount returns the number of parts of the session, which is 1 for a
+// session that does not run a playlist.
+func (c Config) PartCount() int {
+	if len(c.Parts) == 0 {
+		return 1
+	}
+	return len(c.Parts)
+}
+
+// PartConfig returns the configuration that part i of the session runs
+// with. A session that does not run a playlist has only part 0, which runs
+// with c, and so does a part that does not exist.
+func (c Config) PartConfig(i int) Config {
+	if i < 0 || i >= len(c.Parts) {
+		return c
+	}
+	p := c.Parts[i].Config
+	p.Precision = c.Precision
+	p.Keys = c.Keys
+	p.Dimensions = c.Dimensions
+	p.Bands = c.Bands
+	return p
+}
+
+// PartStart returns the elapsed time of the session at which part i starts,
+// after its gap.
+func (c Config) PartStart(i int) time.Duration {
+	var start time.Duration
+	for j, p := range c.Parts {
+		start += p.Gap
+		if j == i {
+			break
+		}
+		start += p.Config.TotalTime
+	}
+	return start
+}
+
+// PartAt returns the part that runs at a certain elapsed time of the session
+// along with the time since the part started. The time since the start is
+// negative during the gap before the part.
+func (c Config) PartAt(elapsed time.Duration) (part int, since time.Duration) {
+	if len(c.Parts) == 0 {
+		return 0, elapsed
+	}
+	var end time.Duration
+	for i, p := range c.Parts {
+		start := end + p.Gap
+		end = start + p.Config.TotalTime
+		if elapsed < end || i == len(c.Parts)-1 {
+			return i, elapsed - start
+		}
+	}
+	return 0, elapsed
+}
@@ -0,0 +1,81 @@
+package session
+
+import "time"
+
+// Segment is a part of a frequency program during which the Hz progress from
+// FromHz to ToHz. A segment with equal FromHz and ToHz holds the frequency.
+type Segment struct {
+	Minutes float64
+	FromHz  float64
+	ToHz    float64
+}
+
+// Duration returns the duration of the segment.
+func (s Segment) Duration() time.Duration {
+	return time.Duration(s.Minutes * float64(time.Minute))
+}
+
+// Hz returns the Hz at time t since the start of the segment, progressing
+// along curve.
+func (s Segment) Hz(t time.Duration, curve Curve) float64 {
+	d := s.Duration()
+	if d <= 0 || t >= d {
+		return s.ToHz
+	}
+	if t <= 0 {
+		return s.FromHz
+	}
+	return s.FromHz + (s.ToHz-s.FromHz)*curve.Progress(t.Seconds()/d.Seconds())
+}
+
+// Program is an ordered list of segments, e.g. hold 14 Hz for 5 minutes,
+// ramp to 10 Hz over 10 minutes and then hold.
+type Program []Segment
+
+// Duration returns the total duration of the program's segments.
+func (p Program) Duration() time.Duration {
+	var d time.Duration
+	for _, s := range p {
+		d += s.Duration()
+	}
+	return d
+}
+
+// StartHz returns the Hz that the program starts with.
+func (p Program) StartHz() float64 {
+	if len(p) == 0 {
+		return 0
+	}
+	return p[0].FromHz
+}
+
+// EndHz returns the Hz that the program ends with.
+func (p Program) EndHz() float64 {
+	if len(p) == 0 {
+		return 0
+	}
+	return p[len(p)-1].ToHz
+}
+
+// At returns the Hz at time t since the start of the program along with the
+// index of the segment that t falls in. The ramp of each segment progresses
+// along curve. Before the start of the program, the Hz of the first
+// segment's start are returned and after its end, the Hz of the last
+// segment's end.
+func (p Program) At(t time.Duration, curve Curve) (hz float64, segment int) {
+	if len(p) == 0 {
+		return 0, 0
+	}
+	if t < 0 {
+		return p[0].FromHz, 0
+	}
+	for i, s := range p {
+		d := s.Duration()
+		if t < d {
+			return s.Hz(t, curve), i
+		}
+		t -= d
+	}
+	last := len(p) - 1
+	return p[last].ToHz, last
+}
@@ -0,0 +1,110 @@
+package session
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestConfigHz(t *testing.T) {
+	programmed := Config{
+		TotalTime: 16 * time.Minute,
+		Offset:    time.Minute,
+		Program: Program{
+			{Minutes: 5, FromHz: 14, ToHz: 14},
+			{Minutes: 10, FromHz: 14, ToHz: 10},
+		},
+	}
+	linear := Config{TotalTime: 11 * time.Minute, Offset: time.Minute, StartHz: 10, EndHz: 5}
+	playlist := NewPlaylist("descent", []Part{
+		{Config: Config{TotalTime: 10 * time.Minute, StartHz: 12, EndHz: 10}},
+		{Gap: 2 * time.Minute, Config: Config{TotalTime: 5 * time.Minute, Offset: time.Minute, StartHz: 8, EndHz: 6}},
+	}, Config{})
+	tests := []struct {
+		name        string
+		config      Config
+		elapsed     time.Duration
+		wantHz      float64
+		wantSegment int
+	}{
+		{"linear during offset", linear, 30 * time.Second, 10, 0},
+		{"linear start", linear, time.Minute, 10, 0},
+		{"linear middle", linear, 6 * time.Minute, 7.5, 0},
+		{"linear end", linear, 11 * time.Minute, 5, 0},
+		{"program during offset", programmed, 0, 14, 0},
+		{"program hold", programmed, 3 * time.Minute, 14, 0},
+		{"program before segment boundary", programmed, 6*time.Minute - time.Nanosecond, 14, 0},
+		{"program segment boundary", programmed, 6 * time.Minute, 14, 1},
+		{"program ramp middle", programmed, 11 * time.Minute, 12, 1},
+		{"program end", programmed, 16 * time.Minute, 10, 1},
+		{"program after end", programmed, 20 * time.Minute, 10, 1},
+		{"first part start", playlist, 0, 12, 0},
+		{"first part end", playlist, 10*time.Minute - time.Nanosecond, 10, 0},
+		{"gap", playlist, 11 * time.Minute, 8, 0},
+		{"second part offset", playlist, 12*time.Minute + 30*time.Second, 8, 0},
+		{"second part middle", playlist, 15 * time.Minute, 7, 0},
+		{"second part end", playlist, 17 * time.Minute, 6, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hz, seg := tt.config.Hz(tt.elapsed)
+			if math.Abs(hz-tt.wantHz) > 1e-9 || seg != tt.wantSegment {
+				t.Errorf("Hz(%v) = %v, %d, want %v, %d", tt.elapsed, hz, seg, tt.wantHz, tt.wantSegment)
+			}
+		})
+	}
+}
+
+func TestPartAt(t *testing.T) {
+	c := NewPlaylist("descent", []Part{
+		{Gap: time.Minute, Config: Config{TotalTime: 10 * time.Minute}},
+		{Gap: 2 * time.Minute, Config: Config{TotalTime: 5 * time.Minute}},
+	}, Config{})
+	tests := []struct {
+		elapsed   time.Duration
+		wantPart  int
+		wantSince time.Duration
+	}{
+		{0, 0, -time.Minute},
+		{time.Minute, 0, 0},
+		{11*time.Minute - time.Nanosecond, 0, 10*time.Minute - time.Nanosecond},
+		{11 * time.Minute, 1, -2 * time.Minute},
+		{13 * time.Minute, 1, 0},
+		{18 * time.Minute, 1, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		part, since := c.PartAt(tt.elapsed)
+		if part != tt.wantPart || since != tt.wantSince {
+			t.Errorf("PartAt(%v) = %d, %v, want %d, %v", tt.elapsed, part, since, tt.wantPart, tt.wantSince)
+		}
+	}
+	if c.TotalTime != 18*time.Minute {
+		t.Errorf("TotalTime = %v, want %v", c.TotalTime, 18*time.Minute)
+	}
+}
+
+func TestSegmentSolve(t *testing.T) {
+	segments := []Segment{
+		{Minutes: 10, FromHz: 14, ToHz: 4},
+		{Minutes: 10, FromHz: 4, ToHz: 14},
+	}
+	times := []time.Duration{
+		time.Second,
+		time.Minute,
+		2*time.Minute + 30*time.Second,
+		5 * time.Minute,
+		7*time.Minute + 123*time.Millisecond,
+		10*time.Minute - time.Second,
+	}
+	for _, curve := range Curves {
+		for _, s := range segments {
+			for _, want := range times {
+				hz := s.Hz(want, curve)
+				got := s.solve(hz, curve)
+				if d := got - want; d < 0 || d > resolution {
+					t.Errorf("%v %v-%v hz: solve(%v) = %v, want %v", curve, s.FromHz, s.ToHz, hz, got, want)
+				}
+			}
+		}
+	}
+}
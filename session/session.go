@@ -0,0 +1,556 @@
+// Package session implements the timing, the key captures and the Hz
+// calculations of a meditation session. It does not depend on the terminal
+// user interface and it reads time from a Clock so that a session can also
+// run in virtual time.
+package session
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Clock provides the current time to a session.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// SystemClock is a Clock that returns the system's time. Time values
+// returned by it carry a monotonic clock reading which protects the elapsed
+// time of a session from wall clock changes.
+var SystemClock Clock = systemClock{}
+
+var (
+	// ErrNotRunning is returned when capturing on a session that has not
+	// been started or has been stopped.
+	ErrNotRunning = errors.New("session is not running")
+	// ErrPaused is returned when capturing on a paused session.
+	ErrPaused = errors.New("session is paused")
+	// ErrOffset is returned when capturing before the offset has passed.
+	ErrOffset = errors.New("key capturing has not started yet")
+	// ErrUnknownKey is returned when capturing a key that is not bound to a
+	// label.
+	ErrUnknownKey = errors.New("key is not bound to a label")
+)
+
+// The modes of a session.
+const (
+	ModeBinaural   = "Binaural"
+	ModeIsochronic = "Isochronic"
+)
+
+// Config holds the parameters that a session runs with.
+type Config struct {
+	// Preset is the name of the configuration preset that the parameters
+	// come from, if any.
+	Preset    string
+	Mode      string
+	TotalTime time.Duration
+	Offset    time.Duration
+	// The base Hz (carrier) progress linearly from StartBaseHz to EndBaseHz,
+	// starting after the offset and until the total time has passed.
+	StartBaseHz float64
+	EndBaseHz   float64
+	StartHz     float64
+	EndHz       float64
+	// Program is the frequency program that starts after the offset. If it
+	// is empty, the Hz progress linearly from StartHz to EndHz until the
+	// total time has passed.
+	Program Program
+	// Curve is the shape of each ramp. It defaults to linear.
+	Curve Curve
+	// Precision is the number of decimals that Hz values are presented
+	// with.
+	Precision int
+	// Keys holds the keys that can be captured, in the order they are
+	// presented.
+	Keys []Binding
+	// Dimensions holds the names of the attributes that the labels of the
+	// keys are classified by.
+	Dimensions []string
+	// Bands holds the brainwave bands that captures are classified in.
+	Bands Bands
+	// Targets holds the Hz whose reaching is a milestone of the session.
+	Targets []float64
+	// Cues selects the points of the session that are announced.
+	Cues Cues
+	// Playlist is the name of the playlist that the session runs, if any.
+	Playlist string `json:",omitempty"`
+	// Parts holds the parts of a playlist session, which run one after the
+	// other. The Hz of such a session are those of the part running.
+	Parts []Part `json:",omitempty"`
+}
+
+// FormatHz returns a string representation of a Hz value using the
+// configured precision.
+func (c Config) FormatHz(hz float64) string {
+	return strconv.FormatFloat(hz, 'f', c.Precision, 64)
+}
+
+// Segments returns the frequency program that the session runs.
+func (c Config) Segments() Program {
+	if len(c.Program) != 0 {
+		return c.Program
+	}
+	linear := Segment{
+		Minutes: (c.TotalTime - c.Offset).Minutes(),
+		FromHz:  c.StartHz,
+		ToHz:    c.EndHz,
+	}
+	return Program{linear}
+}
+
+// Hz returns the Hz at a certain elapsed time of the session along with the
+// index of the program segment it falls in.
+func (c Config) Hz(elapsed time.Duration) (hz float64, segment int) {
+	if len(c.Parts) != 0 {
+		i, t := c.PartAt(elapsed)
+		return c.PartConfig(i).Hz(t)
+	}
+	return c.Segments().At(elapsed-c.Offset, c.Curve)
+}
+
+// BaseHz returns the base Hz at a certain elapsed time of the session.
+func (c Config) BaseHz(elapsed time.Duration) float64 {
+	if len(c.Parts) != 0 {
+		i, t := c.PartAt(elapsed)
+		return c.PartConfig(i).BaseHz(t)
+	}
+	ramp := Segment{
+		Minutes: (c.TotalTime - c.Offset).Minutes(),
+		FromHz:  c.StartBaseHz,
+		ToHz:    c.EndBaseHz,
+	}
+	return ramp.Hz(elapsed-c.Offset, CurveLinear)
+}
+
+// FormatElapsed returns an elapsed time of a session in a timer format with
+// milliseconds, e.g. 04:30.125.
+func FormatElapsed(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d.%03d", ms/60000, ms/1000%60, ms%1000)
+}
+
+// Capture represents a captured key press at a specific time since the start
+// of the session along with the values of Hz and base Hz that were recorded,
+// the brainwave band of the Hz and the index of the program segment and of
+// the playlist part it fell in. The capture of a key bound in interval mode
+// is an interval, which lasts from the press that opened it to the press
+// that closed it.
+type Capture struct {
+	Key        rune
+	Label      string
+	Attributes map[string]string `json:",omitempty"`
+	Time       time.Time         // wall clock time of the capture
+	Elapsed    time.Duration
+	Hz         float64
+	BaseHz     float64
+	Band       string `json:",omitempty"`
+	Segment    int
+	Part       int `json:",omitempty"`
+	// Interval is true for the capture of a key bound in interval mode.
+	Interval bool `json:",omitempty"`
+	// End is the end of an interval. It is nil while the interval is open.
+	End *IntervalEnd `json:",omitempty"`
+}
+
+// Ears returns the frequencies heard by the left and the right ear when the
+// beat of the capture is produced binaurally around its base Hz.
+func (c Capture) Ears() (left, right float64) {
+	return c.BaseHz - c.Hz/2, c.BaseHz + c.Hz/2
+}
+
+// EndReason describes why a session ended.
+type EndReason string
+
+// The reasons a session can end for.
+const (
+	// EndExpired means that the total time of the session has passed.
+	EndExpired EndReason = "expired"
+	// EndStopped means that the session was stopped by the user.
+	EndStopped EndReason = "stopped manually"
+	// EndAborted means that the session was cut short because the program
+	// was quitting.
+	EndAborted EndReason = "aborted"
+)
+
+// Pause represents a period during which the session was frozen.
+type Pause struct {
+	Elapsed  time.Duration // elapsed session time when it was paused
+	Start    time.Time     // wall clock time of the pause
+	Duration time.Duration
+}
+
+// Session is a meditation session. All of its methods are safe for
+// concurrent use.
+type Session struct {
+	mu         sync.Mutex
+	config     Config
+	clock      Clock
+	started    time.Time
+	stopped    time.Time
+	reason     EndReason
+	running    bool
+	paused     bool
+	pause      Pause
+	pausedFor  time.Duration
+	captures   []Capture
+	pauses     []Pause
+	milestones []Milestone
+	cues       []Cue
+	// actions holds the index of the capture of each key press, which is
+	// a new capture or a closed interval, so that the last one can be
+	// undone.
+	actions []int
+	// corrections holds the undone, relabeled and reopened captures.
+	corrections []Correction
+	journal     *Journal
+	// journalErr holds the first error that occurred while writing to the
+	// journal.
+	journalErr error
+}
+
+// New returns a new session that runs with the configuration c and reads
+// time from clock. If clock is nil, the SystemClock is used.
+func New(c Config, clock Clock) *Session {
+	if clock == nil {
+		clock = SystemClock
+	}
+	return &Session{config: c, clock: clock}
+}
+
+// Config returns the configuration of the session.
+func (s *Session) Config() Config {
+	return s.config
+}
+
+// SetJournal sets a journal that each event of the session is written to as
+// it happens. It must be called before Start.
+func (s *Session) SetJournal(j *Journal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.journal = j
+}
+
+// JournalErr returns the first error that occurred while writing to the
+// journal, if any.
+func (s *Session) JournalErr() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.journalErr
+}
+
+func (s *Session) writeJournal(e journalEntry) {
+	if s.journal == nil || s.journalErr != nil {
+		return
+	}
+	s.journalErr = s.journal.write(e)
+}
+
+// Start starts the session.
+func (s *Session) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.started = s.clock.Now()
+	s.running = true
+	s.milestones = s.config.Milestones()
+	s.cues = s.config.CuePoints()
+	s.writeJournal(journalEntry{Type: entryStart, Time: s.started, Config: &s.config})
+}
+
+// Started returns the time the session was started.
+func (s *Session) Started() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.started
+}
+
+// Stop stops the session for a reason. A pause in progress is ended and the
+// intervals that are still open are closed. Stopping a session that is not
+// running has no effect.
+func (s *Session) Stop(reason EndReason) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.running {
+		return
+	}
+	if s.paused {
+		s.resume()
+	}
+	s.stopped = s.clock.Now()
+	s.reason = reason
+	s.running = false
+	for _, c := range s.config.closeIntervals(s.captures, s.stopped, s.elapsed()) {
+		c := c
+		s.writeJournal(journalEntry{Type: entryClose, Time: s.stopped, Capture: &c})
+	}
+	s.writeJournal(journalEntry{Type: entryStop, Time: s.stopped, Reason: reason})
+}
+
+// Running reports whether the session has been started and not stopped.
+func (s *Session) Running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
+
+// Pause freezes the elapsed time of a running session.
+func (s *Session) Pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.running || s.paused {
+		return
+	}
+	s.pause = Pause{Elapsed: s.elapsed(), Start: s.clock.Now()}
+	s.paused = true
+	p := s.pause
+	s.writeJournal(journalEntry{Type: entryPause, Time: p.Start, Pause: &p})
+}
+
+// Resume resumes a paused session.
+func (s *Session) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.paused {
+		s.resume()
+	}
+}
+
+func (s *Session) resume() {
+	s.pause.Duration = s.clock.Now().Sub(s.pause.Start)
+	s.pausedFor += s.pause.Duration
+	s.pauses = append(s.pauses, s.pause)
+	s.paused = false
+	p := s.pause
+	s.writeJournal(journalEntry{Type: entryResume, Time: s.clock.Now(), Pause: &p})
+}
+
+// Paused reports whether the session is paused.
+func (s *Session) Paused() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused
+}
+
+// Elapsed returns the time passed since the start of the session, excluding
+// the time it was paused. It never exceeds the total time of the session.
+func (s *Session) Elapsed() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.elapsed()
+}
+
+func (s *Session) elapsed() time.Duration {
+	if s.started.IsZero() {
+		return 0
+	}
+	now := s.clock.Now()
+	if !s.running {
+		now = s.stopped
+	}
+	if s.paused {
+		now = s.pause.Start
+	}
+	e := now.Sub(s.started) - s.pausedFor
+	if e > s.config.TotalTime {
+		e = s.config.TotalTime
+	}
+	return e
+}
+
+// Remaining returns the time left until the session reaches its total time.
+func (s *Session) Remaining() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.config.TotalTime - s.elapsed()
+}
+
+// Capture records a key press at the current elapsed time of the session.
+// Pressing the key of an open interval closes it instead, and the closed
+// interval is returned.
+func (s *Session) Capture(key rune) (Capture, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.running {
+		return Capture{}, ErrNotRunning
+	}
+	if s.paused {
+		return Capture{}, ErrPaused
+	}
+	b, ok := s.config.Binding(key)
+	if !ok {
+		return Capture{}, ErrUnknownKey
+	}
+	now := s.clock.Now()
+	e := s.elapsed()
+	if i := openInterval(s.captures, key); i >= 0 {
+		s.captures[i].End = s.config.intervalEnd(now, e, false)
+		s.actions = append(s.actions, i)
+		c := s.captures[i]
+		s.writeJournal(journalEntry{Type: entryClose, Time: now, Capture: &c})
+		return c, nil
+	}
+	// Keys are not captured during the offset of each part or the gap
+	// before it.
+	part, t := s.config.PartAt(e)
+	if t < s.config.PartConfig(part).Offset {
+		return Capture{}, ErrOffset
+	}
+	hz, seg := s.config.Hz(e)
+	c := Capture{
+		Key:        key,
+		Label:      b.Label,
+		Attributes: b.Attributes,
+		Time:       now,
+		Elapsed:    e,
+		Hz:         hz,
+		BaseHz:     s.config.BaseHz(e),
+		Band:       s.config.Bands.Classify(hz),
+		Segment:    seg,
+		Part:       part,
+		Interval:   b.Interval,
+	}
+	s.captures = append(s.captures, c)
+	s.actions = append(s.actions, len(s.captures)-1)
+	s.writeJournal(journalEntry{Type: entryCapture, Time: now, Capture: &c})
+	return c, nil
+}
+
+// Captures returns the captures of the session in the order they happened.
+func (s *Session) Captures() []Capture {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Capture(nil), s.captures...)
+}
+
+// Pauses returns the finished pauses of the session in the order they
+// happened.
+func (s *Session) Pauses() []Pause {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Pause(nil), s.pauses...)
+}
+
+// Milestones returns the milestones of the session, which are known from the
+// time it starts.
+func (s *Session) Milestones() []Milestone {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Milestone(nil), s.milestones...)
+}
+
+// NextMilestone returns the first milestone that has not been reached yet.
+// It returns false if there is none.
+func (s *Session) NextMilestone() (Milestone, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.elapsed()
+	for _, m := range s.milestones {
+		if m.Elapsed > e {
+			return m, true
+		}
+	}
+	return Milestone{}, false
+}
+
+// Cues returns the cues of the session, which are known from the time it
+// starts.
+func (s *Session) Cues() []Cue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Cue(nil), s.cues...)
+}
+
+// Record is a snapshot of the data of a session, used for writing logs.
+type Record struct {
+	Config   Config
+	Started  time.Time
+	Stopped  time.Time
+	Captures []Capture
+	Pauses   []Pause
+	// Milestones holds the milestones of the whole session, including the
+	// ones it did not reach.
+	Milestones []Milestone
+	// Cues holds the cues of the whole session, including the ones it did
+	// not reach.
+	Cues []Cue
+	// Corrections holds the corrections made to the captures, which are
+	// already applied to Captures.
+	Corrections []Correction
+	// EndReason is empty for a session that is still running or that was
+	// recovered without having been stopped.
+	EndReason EndReason
+	// Recovered is true when the record was recovered from the journal of
+	// a session that did not finish properly.
+	Recovered bool
+}
+
+// Record returns a snapshot of the session's data. For a session that is
+// still running, Stopped holds the current time.
+func (s *Session) Record() Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := Record{
+		Config:      s.config,
+		Started:     s.started,
+		Stopped:     s.stopped,
+		Captures:    append([]Capture(nil), s.captures...),
+		Pauses:      append([]Pause(nil), s.pauses...),
+		Milestones:  append([]Milestone(nil), s.milestones...),
+		Cues:        append([]Cue(nil), s.cues...),
+		Corrections: append([]Correction(nil), s.corrections...),
+		EndReason:   s.reason,
+	}
+	if s.running {
+		r.Stopped = s.clock.Now()
+	}
+	return r
+}
+
+// ID returns an identifier of the session, based on the time it started.
+func (r Record) ID() string {
+	return r.Started.Format("20060102-150405")
+}
+
+// Reached returns the milestones that the session reached before it stopped.
+func (r Record) Reached() []Milestone {
+	var ms []Milestone
+	e := r.Elapsed()
+	for _, m := range r.Milestones {
+		if m.Elapsed <= e {
+			ms = append(ms, m)
+		}
+	}
+	return ms
+}
+
+// ReachedCues returns the cues that the session reached before it stopped.
+func (r Record) ReachedCues() []Cue {
+	var cues []Cue
+	e := r.Elapsed()
+	for _, c := range r.Cues {
+		if c.Elapsed <= e {
+			cues = append(cues, c)
+		}
+	}
+	return cues
+}
+
+// Elapsed returns the elapsed time of the session at the time it was
+// stopped, excluding the time it was paused.
+func (r Record) Elapsed() time.Duration {
+	e := r.Stopped.Sub(r.Started)
+	for _, p := range r.Pauses {
+		e -= p.Duration
+	}
+	if e > r.Config.TotalTime {
+		e = r.Config.TotalTime
+	}
+	return e
+}
@@ -0,0 +1,177 @@
+package session
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock whose time only moves when it is advanced.
+type fakeClock struct {
+	t time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{t: time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time { return c.t }
+
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestElapsedAndRemaining(t *testing.T) {
+	tests := []struct {
+		name        string
+		run         func(s *Session, c *fakeClock)
+		wantElapsed time.Duration
+	}{
+		{
+			name:        "running",
+			run:         func(s *Session, c *fakeClock) { c.advance(90 * time.Second) },
+			wantElapsed: 90 * time.Second,
+		},
+		{
+			name: "paused",
+			run: func(s *Session, c *fakeClock) {
+				c.advance(30 * time.Second)
+				s.Pause()
+				c.advance(time.Minute)
+			},
+			wantElapsed: 30 * time.Second,
+		},
+		{
+			name: "resumed",
+			run: func(s *Session, c *fakeClock) {
+				c.advance(30 * time.Second)
+				s.Pause()
+				c.advance(time.Minute)
+				s.Resume()
+				c.advance(15 * time.Second)
+			},
+			wantElapsed: 45 * time.Second,
+		},
+		{
+			name: "paused twice",
+			run: func(s *Session, c *fakeClock) {
+				for i := 0; i < 2; i++ {
+					c.advance(10 * time.Second)
+					s.Pause()
+					c.advance(time.Minute)
+					s.Resume()
+				}
+			},
+			wantElapsed: 20 * time.Second,
+		},
+		{
+			name: "stopped",
+			run: func(s *Session, c *fakeClock) {
+				c.advance(10 * time.Second)
+				s.Stop(EndStopped)
+				c.advance(time.Minute)
+			},
+			wantElapsed: 10 * time.Second,
+		},
+		{
+			name: "stopped while paused",
+			run: func(s *Session, c *fakeClock) {
+				c.advance(10 * time.Second)
+				s.Pause()
+				c.advance(time.Minute)
+				s.Stop(EndStopped)
+				c.advance(time.Minute)
+			},
+			wantElapsed: 10 * time.Second,
+		},
+		{
+			name:        "clamped at total time",
+			run:         func(s *Session, c *fakeClock) { c.advance(40 * time.Minute) },
+			wantElapsed: 30 * time.Minute,
+		},
+		{
+			name: "clamped after pause",
+			run: func(s *Session, c *fakeClock) {
+				c.advance(29 * time.Minute)
+				s.Pause()
+				c.advance(5 * time.Minute)
+				s.Resume()
+				c.advance(5 * time.Minute)
+			},
+			wantElapsed: 30 * time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeClock()
+			s := New(Config{TotalTime: 30 * time.Minute}, c)
+			s.Start()
+			tt.run(s, c)
+			if got := s.Elapsed(); got != tt.wantElapsed {
+				t.Errorf("Elapsed() = %v, want %v", got, tt.wantElapsed)
+			}
+			if got, want := s.Remaining(), 30*time.Minute-tt.wantElapsed; got != want {
+				t.Errorf("Remaining() = %v, want %v", got, want)
+			}
+			if got := s.Record().Elapsed(); !s.Running() && got != tt.wantElapsed {
+				t.Errorf("Record().Elapsed() = %v, want %v", got, tt.wantElapsed)
+			}
+		})
+	}
+}
+
+func TestCaptureOffset(t *testing.T) {
+	keys := []Binding{{Key: "q", Label: "Visual memory"}}
+	single := Config{TotalTime: 30 * time.Minute, Offset: 5 * time.Minute, StartHz: 14, EndHz: 8, Keys: keys}
+	part := Config{TotalTime: 10 * time.Minute, Offset: 2 * time.Minute, StartHz: 10, EndHz: 8}
+	playlist := NewPlaylist("descent", []Part{
+		{Config: part},
+		{Gap: time.Minute, Config: part},
+	}, Config{Keys: keys})
+	tests := []struct {
+		name    string
+		config  Config
+		at      time.Duration
+		key     rune
+		wantErr error
+	}{
+		{"start", single, 0, 'q', ErrOffset},
+		{"before offset", single, 5*time.Minute - time.Millisecond, 'q', ErrOffset},
+		{"at offset", single, 5 * time.Minute, 'q', nil},
+		{"unknown key", single, 10 * time.Minute, 'x', ErrUnknownKey},
+		{"first part offset", playlist, time.Minute, 'q', ErrOffset},
+		{"first part", playlist, 2 * time.Minute, 'q', nil},
+		{"gap", playlist, 10*time.Minute + 30*time.Second, 'q', ErrOffset},
+		{"second part offset", playlist, 12 * time.Minute, 'q', ErrOffset},
+		{"second part", playlist, 13 * time.Minute, 'q', nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeClock()
+			s := New(tt.config, c)
+			s.Start()
+			c.advance(tt.at)
+			capt, err := s.Capture(tt.key)
+			if err != tt.wantErr {
+				t.Fatalf("Capture(%q) err = %v, want %v", tt.key, err, tt.wantErr)
+			}
+			if err == nil && capt.Elapsed != tt.at {
+				t.Errorf("Capture(%q).Elapsed = %v, want %v", tt.key, capt.Elapsed, tt.at)
+			}
+		})
+	}
+}
+
+func TestCaptureNotRunning(t *testing.T) {
+	c := newFakeClock()
+	s := New(Config{TotalTime: time.Minute, Keys: []Binding{{Key: "q", Label: "Q"}}}, c)
+	if _, err := s.Capture('q'); err != ErrNotRunning {
+		t.Errorf("Capture before Start err = %v, want %v", err, ErrNotRunning)
+	}
+	s.Start()
+	s.Pause()
+	if _, err := s.Capture('q'); err != ErrPaused {
+		t.Errorf("Capture while paused err = %v, want %v", err, ErrPaused)
+	}
+	s.Stop(EndStopped)
+	if _, err := s.Capture('q'); err != ErrNotRunning {
+		t.Errorf("Capture after Stop err = %v, want %v", err, ErrNotRunning)
+	}
+}
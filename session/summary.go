@@ -0,0 +1,82 @@
+package session
+
+import "sort"
+
+// Group holds statistics about the captures that share the same value, e.g.
+// all captures with the "Imagination" value of the "Process" attribute.
+type Group struct {
+	Value  string
+	Count  int
+	MinHz  float64
+	MaxHz  float64
+	MeanHz float64
+}
+
+// GroupBy groups captures by the value that key returns for each of them.
+// Captures for which key returns an empty value are left out. The groups
+// are sorted by value.
+func GroupBy(captures []Capture, key func(Capture) string) []Group {
+	groups := make(map[string]*Group)
+	var values []string
+	for _, c := range captures {
+		v := key(c)
+		if v == "" {
+			continue
+		}
+		g, ok := groups[v]
+		if !ok {
+			g = &Group{Value: v, MinHz: c.Hz, MaxHz: c.Hz}
+			groups[v] = g
+			values = append(values, v)
+		}
+		if c.Hz < g.MinHz {
+			g.MinHz = c.Hz
+		}
+		if c.Hz > g.MaxHz {
+			g.MaxHz = c.Hz
+		}
+		// Keeping the running mean.
+		g.Count++
+		g.MeanHz += (c.Hz - g.MeanHz) / float64(g.Count)
+	}
+	sort.Strings(values)
+	result := make([]Group, len(values))
+	for i, v := range values {
+		result[i] = *groups[v]
+	}
+	return result
+}
+
+// ByAttribute returns a key for GroupBy that groups captures by the value of
+// one of their attributes.
+func ByAttribute(name string) func(Capture) string {
+	return func(c Capture) string {
+		return c.Attributes[name]
+	}
+}
+
+// ByLabel is a key for GroupBy that groups captures by their label.
+func ByLabel(c Capture) string {
+	return c.Label
+}
+
+// ByBand is a key for GroupBy that groups captures by their brainwave band.
+func ByBand(c Capture) string {
+	return c.Band
+}
+
+// GroupByBand groups captures by their brainwave band. The groups are sorted
+// in the order of bands.
+func GroupByBand(captures []Capture, bands Bands) []Group {
+	groups := make(map[string]Group)
+	for _, g := range GroupBy(captures, ByBand) {
+		groups[g.Value] = g
+	}
+	var sorted []Group
+	for _, b := range bands {
+		if g, ok := groups[b.Name]; ok {
+			sorted = append(sorted, g)
+		}
+	}
+	return sorted
+}
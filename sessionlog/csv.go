@@ -0,0 +1,66 @@
+package sessionlog
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/nstratos/mdt/session"
+)
+
+var csvHeader = []string{
+	"session_id", "time", "elapsed_seconds", "key", "label", "hz",
+	"base_hz", "mode", "start_hz", "end_hz", "offset_minutes", "band",
+	"preset", "playlist", "part", "interval_end_elapsed_seconds",
+	"interval_end_hz", "interval_duration_seconds",
+}
+
+// WriteCSV writes the captures of a record as comma separated values, one row
+// per capture, preceded by a header row. Each row carries the full context
+// of the capture so that rows of different sessions can be combined. The
+// name of the log is not written. The context of a capture of a playlist
+// session is that of the part it fell in. The interval columns are empty for
+// the captures that are not intervals.
+func WriteCSV(w io.Writer, name string, r session.Record) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, capt := range r.Captures {
+		c := r.Config.PartConfig(capt.Part)
+		program := c.Segments()
+		// The values are set by column so that they cannot drift from
+		// the header.
+		col := map[string]string{
+			"session_id":      r.ID(),
+			"time":            capt.Time.Format("2006-01-02T15:04:05.000Z07:00"),
+			"elapsed_seconds": strconv.FormatFloat(capt.Elapsed.Seconds(), 'f', 3, 64),
+			"key":             string(capt.Key),
+			"label":           capt.Label,
+			"hz":              c.FormatHz(capt.Hz),
+			"base_hz":         c.FormatHz(capt.BaseHz),
+			"mode":            c.Mode,
+			"start_hz":        c.FormatHz(program.StartHz()),
+			"end_hz":          c.FormatHz(program.EndHz()),
+			"offset_minutes":  strconv.FormatFloat(c.Offset.Minutes(), 'f', -1, 64),
+			"band":            capt.Band,
+			"preset":          c.Preset,
+			"playlist":        r.Config.Playlist,
+			"part":            strconv.Itoa(capt.Part + 1),
+		}
+		if capt.End != nil {
+			col["interval_end_elapsed_seconds"] = strconv.FormatFloat(capt.End.Elapsed.Seconds(), 'f', 3, 64)
+			col["interval_end_hz"] = c.FormatHz(capt.End.Hz)
+			col["interval_duration_seconds"] = strconv.FormatFloat(capt.Duration().Seconds(), 'f', 3, 64)
+		}
+		row := make([]string, len(csvHeader))
+		for i, name := range csvHeader {
+			row[i] = col[name]
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
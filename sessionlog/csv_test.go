@@ -0,0 +1,62 @@
+package sessionlog
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/nstratos/mdt/session"
+)
+
+func TestWriteCSV(t *testing.T) {
+	started := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)
+	r := session.Record{
+		Config: session.Config{
+			Mode:      session.ModeBinaural,
+			TotalTime: 31 * time.Minute,
+			Offset:    time.Minute,
+			StartHz:   14,
+			EndHz:     8,
+			Precision: 2,
+			Preset:    "descent",
+		},
+		Started: started,
+		Stopped: started.Add(10 * time.Minute),
+		Captures: []session.Capture{
+			{
+				Key: 'q', Label: "Visual memory", Time: started.Add(90 * time.Second),
+				Elapsed: 90 * time.Second, Hz: 13.9, BaseHz: 100, Band: "beta",
+			},
+			{
+				Key: 'f', Label: "Floating, calm", Time: started.Add(2 * time.Minute),
+				Elapsed: 2 * time.Minute, Hz: 13.8, BaseHz: 100, Band: "beta", Interval: true,
+				End: &session.IntervalEnd{Elapsed: 5*time.Minute + 30*time.Second + 250*time.Millisecond, Hz: 13.1},
+			},
+		},
+	}
+	want := "" +
+		"session_id,time,elapsed_seconds,key,label,hz,base_hz,mode,start_hz,end_hz,offset_minutes,band," +
+		"preset,playlist,part,interval_end_elapsed_seconds,interval_end_hz,interval_duration_seconds\n" +
+		"20200101-100000,2020-01-01T10:01:30.000Z,90.000,q,Visual memory,13.90,100.00,Binaural,14.00,8.00,1,beta," +
+		"descent,,1,,,\n" +
+		"20200101-100000,2020-01-01T10:02:00.000Z,120.000,f,\"Floating, calm\",13.80,100.00,Binaural,14.00,8.00,1,beta," +
+		"descent,,1,330.250,13.10,210.250\n"
+	var b bytes.Buffer
+	if err := WriteCSV(&b, "14-8 hz Wed 01 Jan 10.00", r); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.String(); got != want {
+		t.Errorf("WriteCSV() =\n%s\nwant\n%s", got, want)
+	}
+	// Each row has a value for each column of the header.
+	rows, err := csv.NewReader(&b).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, row := range rows {
+		if len(row) != len(csvHeader) {
+			t.Errorf("row %d has %d columns, want %d", i, len(row), len(csvHeader))
+		}
+	}
+}
@@ -0,0 +1,390 @@
+package sessionlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/nstratos/mdt/session"
+)
+
+// SchemaVersion is the version of the JSON session document. It is increased
+// whenever the document changes in a way that is not backwards compatible.
+const SchemaVersion = 1
+
+// Document is the JSON session document, a machine readable record of a
+// session with everything needed to analyze it again later. Durations are
+// kept in seconds or minutes, as their field names say.
+type Document struct {
+	Schema    int
+	Version   string // version of the program that wrote the document
+	ID        string
+	Name      string
+	Started   time.Time
+	Stopped   time.Time
+	EndReason session.EndReason `json:",omitempty"`
+	Recovered bool              `json:",omitempty"`
+	// ElapsedSeconds is the elapsed time of the session when it stopped,
+	// excluding the time it was paused.
+	ElapsedSeconds float64
+	Config         DocumentConfig
+	Captures       []DocumentCapture
+	Pauses         []DocumentPause     `json:",omitempty"`
+	Milestones     []DocumentMilestone `json:",omitempty"`
+	Cues           []DocumentCue       `json:",omitempty"`
+	// Corrections holds the undone, relabeled and reopened captures. They are
+	// already applied to Captures.
+	Corrections []DocumentCorrection `json:",omitempty"`
+}
+
+// DocumentConfig is the snapshot of the configuration that a session ran
+// with.
+type DocumentConfig struct {
+	Preset           string `json:",omitempty"`
+	Mode             string
+	TotalTimeMinutes float64
+	OffsetMinutes    float64
+	StartBaseHz      float64
+	EndBaseHz        float64
+	StartHz          float64
+	EndHz            float64
+	Program          session.Program `json:",omitempty"`
+	Curve            session.Curve
+	// Precision and Keys are left out of the configuration of a part,
+	// like Dimensions and Bands.
+	Precision  int               `json:",omitempty"`
+	Keys       []session.Binding `json:",omitempty"`
+	Dimensions []string          `json:",omitempty"`
+	Bands      session.Bands     `json:",omitempty"`
+	Targets    []float64         `json:",omitempty"`
+	Cues       session.Cues
+	Playlist   string         `json:",omitempty"`
+	Parts      []DocumentPart `json:",omitempty"`
+}
+
+// DocumentPart is a part of a playlist session. Its configuration shares
+// the precision, the keys, the dimensions and the bands of the session.
+type DocumentPart struct {
+	GapMinutes float64
+	Config     DocumentConfig
+}
+
+// DocumentCapture is a capture of a session.
+type DocumentCapture struct {
+	Key            string
+	Label          string
+	Attributes     map[string]string `json:",omitempty"`
+	Time           time.Time
+	ElapsedSeconds float64
+	Hz             float64
+	BaseHz         float64
+	Band           string `json:",omitempty"`
+	Segment        int
+	Part           int `json:",omitempty"`
+	// Interval is true for the capture of a key bound in interval mode,
+	// which lasts until End.
+	Interval bool                 `json:",omitempty"`
+	End      *DocumentIntervalEnd `json:",omitempty"`
+}
+
+// DocumentIntervalEnd is the end of an interval capture. Auto is true when
+// the interval was closed because the session ended.
+type DocumentIntervalEnd struct {
+	Time            time.Time
+	ElapsedSeconds  float64
+	DurationSeconds float64
+	Hz              float64
+	BaseHz          float64
+	Band            string `json:",omitempty"`
+	Segment         int
+	Part            int  `json:",omitempty"`
+	Auto            bool `json:",omitempty"`
+}
+
+// DocumentPause is a pause of a session.
+type DocumentPause struct {
+	ElapsedSeconds  float64
+	Start           time.Time
+	DurationSeconds float64
+}
+
+// DocumentMilestone is a milestone of a session, including the ones that it
+// did not reach.
+type DocumentMilestone struct {
+	Kind           session.MilestoneKind
+	ElapsedSeconds float64
+	Hz             float64
+	Band           string `json:",omitempty"`
+}
+
+// DocumentCue is a cue of a session, including the ones that it did not
+// reach.
+type DocumentCue struct {
+	Kind           session.CueKind
+	ElapsedSeconds float64
+	Band           string `json:",omitempty"`
+}
+
+// DocumentCorrection is a correction of the last capture of a session. Key,
+// Label and Attributes are those that a relabeled capture got.
+type DocumentCorrection struct {
+	Kind           session.CorrectionKind
+	Time           time.Time
+	ElapsedSeconds float64
+	Capture        DocumentCapture
+	Key            string            `json:",omitempty"`
+	Label          string            `json:",omitempty"`
+	Attributes     map[string]string `json:",omitempty"`
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// NewDocument returns the document of a record named name, written by the
+// given version of the program.
+func NewDocument(name string, r session.Record, version string) Document {
+	d := Document{
+		Schema:         SchemaVersion,
+		Version:        version,
+		ID:             r.ID(),
+		Name:           name,
+		Started:        r.Started,
+		Stopped:        r.Stopped,
+		EndReason:      r.EndReason,
+		Recovered:      r.Recovered,
+		ElapsedSeconds: r.Elapsed().Seconds(),
+		Config:         newDocumentConfig(r.Config),
+		Captures:       make([]DocumentCapture, len(r.Captures)),
+	}
+	for i, capt := range r.Captures {
+		d.Captures[i] = newDocumentCapture(capt)
+	}
+	for _, p := range r.Pauses {
+		d.Pauses = append(d.Pauses, DocumentPause{
+			ElapsedSeconds:  p.Elapsed.Seconds(),
+			Start:           p.Start,
+			DurationSeconds: p.Duration.Seconds(),
+		})
+	}
+	for _, m := range r.Milestones {
+		d.Milestones = append(d.Milestones, DocumentMilestone{
+			Kind:           m.Kind,
+			ElapsedSeconds: m.Elapsed.Seconds(),
+			Hz:             m.Hz,
+			Band:           m.Band,
+		})
+	}
+	for _, c := range r.Cues {
+		d.Cues = append(d.Cues, DocumentCue{
+			Kind:           c.Kind,
+			ElapsedSeconds: c.Elapsed.Seconds(),
+			Band:           c.Band,
+		})
+	}
+	for _, c := range r.Corrections {
+		dc := DocumentCorrection{
+			Kind:           c.Kind,
+			Time:           c.Time,
+			ElapsedSeconds: c.Elapsed.Seconds(),
+			Capture:        newDocumentCapture(c.Capture),
+			Label:          c.Label,
+			Attributes:     c.Attributes,
+		}
+		if c.Key != 0 {
+			dc.Key = string(c.Key)
+		}
+		d.Corrections = append(d.Corrections, dc)
+	}
+	return d
+}
+
+func newDocumentCapture(c session.Capture) DocumentCapture {
+	dc := DocumentCapture{
+		Key:            string(c.Key),
+		Label:          c.Label,
+		Attributes:     c.Attributes,
+		Time:           c.Time,
+		ElapsedSeconds: c.Elapsed.Seconds(),
+		Hz:             c.Hz,
+		BaseHz:         c.BaseHz,
+		Band:           c.Band,
+		Segment:        c.Segment,
+		Part:           c.Part,
+		Interval:       c.Interval,
+	}
+	if c.End != nil {
+		dc.End = &DocumentIntervalEnd{
+			Time:            c.End.Time,
+			ElapsedSeconds:  c.End.Elapsed.Seconds(),
+			DurationSeconds: c.Duration().Seconds(),
+			Hz:              c.End.Hz,
+			BaseHz:          c.End.BaseHz,
+			Band:            c.End.Band,
+			Segment:         c.End.Segment,
+			Part:            c.End.Part,
+			Auto:            c.End.Auto,
+		}
+	}
+	return dc
+}
+
+func (dc DocumentCapture) capture() session.Capture {
+	b := session.Binding{Key: dc.Key}
+	c := session.Capture{
+		Key:        b.Rune(),
+		Label:      dc.Label,
+		Attributes: dc.Attributes,
+		Time:       dc.Time,
+		Elapsed:    seconds(dc.ElapsedSeconds),
+		Hz:         dc.Hz,
+		BaseHz:     dc.BaseHz,
+		Band:       dc.Band,
+		Segment:    dc.Segment,
+		Part:       dc.Part,
+		Interval:   dc.Interval,
+	}
+	if dc.End != nil {
+		c.End = &session.IntervalEnd{
+			Time:    dc.End.Time,
+			Elapsed: seconds(dc.End.ElapsedSeconds),
+			Hz:      dc.End.Hz,
+			BaseHz:  dc.End.BaseHz,
+			Band:    dc.End.Band,
+			Segment: dc.End.Segment,
+			Part:    dc.End.Part,
+			Auto:    dc.End.Auto,
+		}
+	}
+	return c
+}
+
+// Record returns the record of the session that the document describes.
+func (d Document) Record() session.Record {
+	r := session.Record{
+		Config:    d.Config.config(),
+		Started:   d.Started,
+		Stopped:   d.Stopped,
+		EndReason: d.EndReason,
+		Recovered: d.Recovered,
+	}
+	for _, dc := range d.Captures {
+		r.Captures = append(r.Captures, dc.capture())
+	}
+	for _, dp := range d.Pauses {
+		r.Pauses = append(r.Pauses, session.Pause{
+			Elapsed:  seconds(dp.ElapsedSeconds),
+			Start:    dp.Start,
+			Duration: seconds(dp.DurationSeconds),
+		})
+	}
+	for _, dm := range d.Milestones {
+		r.Milestones = append(r.Milestones, session.Milestone{
+			Kind:    dm.Kind,
+			Elapsed: seconds(dm.ElapsedSeconds),
+			Hz:      dm.Hz,
+			Band:    dm.Band,
+		})
+	}
+	for _, dc := range d.Cues {
+		r.Cues = append(r.Cues, session.Cue{
+			Kind:    dc.Kind,
+			Elapsed: seconds(dc.ElapsedSeconds),
+			Band:    dc.Band,
+		})
+	}
+	for _, dc := range d.Corrections {
+		c := session.Correction{
+			Kind:       dc.Kind,
+			Time:       dc.Time,
+			Elapsed:    seconds(dc.ElapsedSeconds),
+			Capture:    dc.Capture.capture(),
+			Label:      dc.Label,
+			Attributes: dc.Attributes,
+		}
+		if dc.Key != "" {
+			c.Key = session.Binding{Key: dc.Key}.Rune()
+		}
+		r.Corrections = append(r.Corrections, c)
+	}
+	return r
+}
+
+func newDocumentConfig(c session.Config) DocumentConfig {
+	dc := DocumentConfig{
+		Preset:           c.Preset,
+		Mode:             c.Mode,
+		TotalTimeMinutes: c.TotalTime.Minutes(),
+		OffsetMinutes:    c.Offset.Minutes(),
+		StartBaseHz:      c.StartBaseHz,
+		EndBaseHz:        c.EndBaseHz,
+		StartHz:          c.StartHz,
+		EndHz:            c.EndHz,
+		Program:          c.Program,
+		Curve:            c.Curve,
+		Precision:        c.Precision,
+		Keys:             c.Keys,
+		Dimensions:       c.Dimensions,
+		Bands:            c.Bands,
+		Targets:          c.Targets,
+		Cues:             c.Cues,
+		Playlist:         c.Playlist,
+	}
+	for _, p := range c.Parts {
+		dc.Parts = append(dc.Parts, DocumentPart{
+			GapMinutes: p.Gap.Minutes(),
+			Config:     newDocumentConfig(p.Config),
+		})
+	}
+	return dc
+}
+
+func (dc DocumentConfig) config() session.Config {
+	c := session.Config{
+		Preset:      dc.Preset,
+		Mode:        dc.Mode,
+		TotalTime:   time.Duration(dc.TotalTimeMinutes * float64(time.Minute)),
+		Offset:      time.Duration(dc.OffsetMinutes * float64(time.Minute)),
+		StartBaseHz: dc.StartBaseHz,
+		EndBaseHz:   dc.EndBaseHz,
+		StartHz:     dc.StartHz,
+		EndHz:       dc.EndHz,
+		Program:     dc.Program,
+		Curve:       dc.Curve,
+		Precision:   dc.Precision,
+		Keys:        dc.Keys,
+		Dimensions:  dc.Dimensions,
+		Bands:       dc.Bands,
+		Targets:     dc.Targets,
+		Cues:        dc.Cues,
+		Playlist:    dc.Playlist,
+	}
+	for _, p := range dc.Parts {
+		c.Parts = append(c.Parts, session.Part{
+			Gap:    time.Duration(p.GapMinutes * float64(time.Minute)),
+			Config: p.Config.config(),
+		})
+	}
+	return c
+}
+
+// WriteJSON writes a document as indented JSON.
+func WriteJSON(w io.Writer, d Document) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+// ReadJSON reads a document. It returns an error if the document has a
+// schema version that it does not know of.
+func ReadJSON(r io.Reader) (Document, error) {
+	var d Document
+	if err := json.NewDecoder(r).Decode(&d); err != nil {
+		return Document{}, err
+	}
+	if d.Schema < 1 || d.Schema > SchemaVersion {
+		return Document{}, fmt.Errorf("unsupported session document schema %d", d.Schema)
+	}
+	return d, nil
+}
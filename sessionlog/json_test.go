@@ -0,0 +1,153 @@
+package sessionlog
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nstratos/mdt/session"
+)
+
+// jsonRecord runs a playlist session with point and interval captures, a
+// pause and corrections, and returns its record.
+func jsonRecord(t *testing.T) session.Record {
+	t.Helper()
+	c := &testClock{t: time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)}
+	s := session.New(session.NewPlaylist("descent", []session.Part{
+		{Config: session.Config{Preset: "warm-up", Mode: session.ModeBinaural, TotalTime: 5 * time.Minute,
+			StartHz: 14, EndHz: 12, StartBaseHz: 100, EndBaseHz: 100}},
+		{Gap: time.Minute, Config: session.Config{Mode: session.ModeIsochronic, TotalTime: 10 * time.Minute,
+			Offset: time.Minute, Curve: session.CurveSigmoid, StartBaseHz: 120, EndBaseHz: 100,
+			Program: session.Program{{Minutes: 4, FromHz: 12, ToHz: 12}, {Minutes: 5, FromHz: 12, ToHz: 7}},
+			Targets: []float64{10}}},
+	}, session.Config{
+		Precision:  2,
+		Keys:       testKeys,
+		Dimensions: []string{"Modality", "Process"},
+		Bands:      session.DefaultBands,
+		Cues:       session.Cues{Every: 4, Bands: true, LastMinute: true},
+	}), c)
+	s.Start()
+	at := func(d time.Duration, key rune) {
+		t.Helper()
+		c.advance(d)
+		if _, err := s.Capture(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	at(time.Minute, 'q')
+	at(time.Minute, 'f')
+	c.advance(30 * time.Second)
+	s.Pause()
+	c.advance(time.Minute)
+	s.Resume()
+	at(6*time.Minute, 'a')
+	if _, err := s.Relabel('w'); err != nil {
+		t.Fatal(err)
+	}
+	at(time.Minute, 'f')
+	if _, err := s.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	at(time.Minute+500*time.Millisecond, 'd')
+	c.advance(time.Minute)
+	s.Stop(session.EndStopped)
+	return s.Record()
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	want := jsonRecord(t)
+	var b bytes.Buffer
+	if err := WriteJSON(&b, NewDocument("descent Wed 01 Jan 10.00", want, "1.2.0")); err != nil {
+		t.Fatal(err)
+	}
+	d, err := ReadJSON(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Name != "descent Wed 01 Jan 10.00" || d.Version != "1.2.0" || d.ID != want.ID() {
+		t.Errorf("document %q %q %q, want its name, version and ID", d.Name, d.Version, d.ID)
+	}
+	got := d.Record()
+	if !reflect.DeepEqual(got.Config, want.Config) {
+		t.Errorf("Config = %+v, want %+v", got.Config, want.Config)
+	}
+	if !got.Started.Equal(want.Started) || !got.Stopped.Equal(want.Stopped) || got.EndReason != want.EndReason {
+		t.Errorf("Started, Stopped, EndReason = %v %v %q, want %v %v %q",
+			got.Started, got.Stopped, got.EndReason, want.Started, want.Stopped, want.EndReason)
+	}
+	if !reflect.DeepEqual(got.Captures, want.Captures) {
+		t.Errorf("Captures = %+v, want %+v", got.Captures, want.Captures)
+	}
+	if !reflect.DeepEqual(got.Pauses, want.Pauses) {
+		t.Errorf("Pauses = %+v, want %+v", got.Pauses, want.Pauses)
+	}
+	if !reflect.DeepEqual(got.Corrections, want.Corrections) {
+		t.Errorf("Corrections = %+v, want %+v", got.Corrections, want.Corrections)
+	}
+	// The times of milestones and cues are found to the nanosecond, which
+	// the seconds of the document may not keep.
+	near := func(a, b time.Duration) bool { return a-b <= time.Microsecond && b-a <= time.Microsecond }
+	if len(got.Milestones) != len(want.Milestones) || len(want.Milestones) == 0 {
+		t.Fatalf("Milestones = %+v, want %+v", got.Milestones, want.Milestones)
+	}
+	for i, m := range want.Milestones {
+		g := got.Milestones[i]
+		if g.Kind != m.Kind || g.Hz != m.Hz || g.Band != m.Band || !near(g.Elapsed, m.Elapsed) {
+			t.Errorf("milestone %d = %+v, want %+v", i, g, m)
+		}
+	}
+	if len(got.Cues) != len(want.Cues) || len(want.Cues) == 0 {
+		t.Fatalf("Cues = %+v, want %+v", got.Cues, want.Cues)
+	}
+	for i, c := range want.Cues {
+		g := got.Cues[i]
+		if g.Kind != c.Kind || g.Band != c.Band || !near(g.Elapsed, c.Elapsed) {
+			t.Errorf("cue %d = %+v, want %+v", i, g, c)
+		}
+	}
+	if got.Elapsed() != want.Elapsed() {
+		t.Errorf("Elapsed() = %v, want %v", got.Elapsed(), want.Elapsed())
+	}
+}
+
+func TestJSONPartsShareSessionConfig(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteJSON(&b, NewDocument("descent", jsonRecord(t), "devel")); err != nil {
+		t.Fatal(err)
+	}
+	d, err := ReadJSON(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Config.Keys) == 0 || d.Config.Precision != 2 {
+		t.Errorf("session Keys, Precision = %v, %d, want the shared ones", d.Config.Keys, d.Config.Precision)
+	}
+	// Only the configuration of the session holds the keys and the
+	// precision, the parts leave them out.
+	if n := strings.Count(b.String(), `"Keys"`); n != 1 {
+		t.Errorf("document has %d Keys fields, want 1:\n%s", n, b.String())
+	}
+	if n := strings.Count(b.String(), `"Precision"`); n != 1 {
+		t.Errorf("document has %d Precision fields, want 1", n)
+	}
+}
+
+func TestReadJSONSchema(t *testing.T) {
+	tests := []struct {
+		in      string
+		wantErr bool
+	}{
+		{`{"Schema": 1}`, false},
+		{`{"Schema": 0}`, true},
+		{`{"Schema": 2}`, true},
+		{`{"Schema": `, true},
+	}
+	for _, tt := range tests {
+		if _, err := ReadJSON(strings.NewReader(tt.in)); (err != nil) != tt.wantErr {
+			t.Errorf("ReadJSON(%s) err = %v, want error %v", tt.in, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,537 @@
+package sessionlog
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nstratos/mdt/session"
+)
+
+var (
+	// '15-19 hz wed 27 dec 22.09', optionally followed by ' (2)'.
+	nameRe = regexp.MustCompile(`^(\d+(?:[.,]\d+)?)-(\d+(?:[.,]\d+)?) hz (\w{3} \d{2} \w{3} \d{2}\.\d{2})(?: \(\d+\))?$`)
+	// '15.05hz @ 80.00 base hz, on 04:30 Visual memory' written by all
+	// versions, with the band, the ear frequencies, the milliseconds, the
+	// program segment and the playlist part added by later ones.
+	captureRe = regexp.MustCompile(`^(\d+(?:[.,]\d+)?) ?hz(?: \(([^)]+)\))? @ (\d+(?:[.,]\d+)?) base hz` +
+		`(?: \((-?\d+(?:[.,]\d+)?)hz left, (-?\d+(?:[.,]\d+)?)hz right\))?, ` +
+		`on (\d+:\d{2}(?:[.,]\d{3})?) (.+?)` +
+		`(?: until (\d+:\d{2}\.\d{3}) @ (\d+(?:[.,]\d+)?)hz(?: \(([^)]+)\))? for \d+:\d{2}\.\d{3}( \(closed at end\))?)?` +
+		`(?: \(segment (\d+)/\d+\))?(?: \(part (\d+)/\d+\))?$`)
+	pausedRe   = regexp.MustCompile(`^Paused on (\d+:\d{2}(?:\.\d{3})?) \((\d{2}:\d{2}:\d{2})\)$`)
+	resumedRe  = regexp.MustCompile(`^Resumed on (\d+:\d{2}(?:\.\d{3})?) \((\d{2}:\d{2}:\d{2})\), paused for (\d+:\d{2}(?:\.\d{3})?)$`)
+	enteringRe = regexp.MustCompile(`^Entering (.+) on (\d+:\d{2}\.\d{3}) \((\d+(?:[.,]\d+)?)hz\)$`)
+	reachingRe = regexp.MustCompile(`^Reaching (\d+(?:[.,]\d+)?)hz on (\d+:\d{2}\.\d{3})$`)
+	cueRe      = regexp.MustCompile(`^Cue \((.+)\) on (\d+:\d{2}\.\d{3})$`)
+	undidRe    = regexp.MustCompile(`^Undid capture of (\d+:\d{2}\.\d{3}) (.+) on (\d+:\d{2}\.\d{3})$`)
+	relabelRe  = regexp.MustCompile(`^Relabeled capture of (\d+:\d{2}\.\d{3}) (.+?) as (.+) on (\d+:\d{2}\.\d{3})$`)
+	reopenRe   = regexp.MustCompile(`^Reopened interval of (\d+:\d{2}\.\d{3}) (.+) on (\d+:\d{2}\.\d{3})$`)
+	partRe     = regexp.MustCompile(`^Part (\d+)/\d+ \((.*)\) started on (\d+:\d{2}\.\d{3})$`)
+	abortedRe  = regexp.MustCompile(`^Aborted at (\d+:\d{2}(?:\.\d{3})?)$`)
+	minutesRe  = regexp.MustCompile(`^(\d+(?:\.\d+)?) min$`)
+	segmentRe  = regexp.MustCompile(`^(\d+(?:\.\d+)?) min (\d+(?:[.,]\d+)?)-(\d+(?:[.,]\d+)?) hz$`)
+	rangeRe    = regexp.MustCompile(`^(\d+(?:[.,]\d+)?)-(\d+(?:[.,]\d+)?)$`)
+)
+
+var errUnknownLine = errors.New("unknown line")
+
+// ParseError describes a line of a log that could not be parsed.
+type ParseError struct {
+	Line int
+	Text string
+	Err  error
+}
+
+func (e ParseError) Error() string {
+	return fmt.Sprintf("line %d %q: %v", e.Line, e.Text, e.Err)
+}
+
+// Parsed is a session read back from a text log.
+type Parsed struct {
+	Name   string
+	Record session.Record
+	// Errors holds the lines that could not be parsed.
+	Errors []ParseError
+}
+
+// Parser reads text logs, as written by WriteText by this and by older
+// versions of the program, back into records. Text logs do not keep
+// everything a session document does: a session parsed from a text log has
+// the time it ended, taken from its name, but not the time it started, and
+// its captures have their elapsed time but not their wall clock time.
+type Parser struct {
+	// Keys are used to find the key and the attributes of each captured
+	// label, as text logs only keep the label.
+	Keys []session.Binding
+}
+
+// ParseFile parses the text log at path.
+func (p Parser) ParseFile(path string) (Parsed, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Parsed{}, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return Parsed{}, err
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return p.Parse(f, name, fi.ModTime())
+}
+
+// Parse parses a text log named name. Older logs do not have the year in
+// their name, so it is guessed as the latest year up to the year of modTime
+// which has the same day of the week. Lines that cannot be parsed are
+// reported in the Errors of the result.
+func (p Parser) Parse(r io.Reader, name string, modTime time.Time) (Parsed, error) {
+	ps := Parsed{Name: name}
+	rec := &ps.Record
+	rec.Config.Curve = session.CurveLinear
+	rec.Config.Precision = 2
+	var pause session.Pause
+	paused := false
+	inSummary := false
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		fail := func(err error) {
+			ps.Errors = append(ps.Errors, ParseError{Line: n, Text: line, Err: err})
+		}
+		// The first line is the name of the log.
+		if n == 1 {
+			ps.Name = line
+			if err := p.parseName(rec, line, modTime); err != nil {
+				fail(err)
+			}
+			continue
+		}
+		if inSummary && strings.HasPrefix(line, "  ") {
+			continue
+		}
+		inSummary = false
+		if m := captureRe.FindStringSubmatch(line); m != nil {
+			c, err := p.parseCapture(m)
+			if err != nil {
+				fail(err)
+				continue
+			}
+			if len(rec.Captures) == 0 {
+				rec.Config.Precision = decimals(m[1])
+			}
+			rec.Captures = append(rec.Captures, c)
+			continue
+		}
+		if m := pausedRe.FindStringSubmatch(line); m != nil {
+			e, err := parseElapsed(m[1])
+			if err != nil {
+				fail(err)
+				continue
+			}
+			pause = session.Pause{Elapsed: e, Start: clock(rec.Stopped, m[2])}
+			paused = true
+			continue
+		}
+		if m := resumedRe.FindStringSubmatch(line); m != nil {
+			if !paused {
+				fail(errors.New("resumed without being paused"))
+				continue
+			}
+			d, err := parseElapsed(m[3])
+			if err != nil {
+				fail(err)
+				continue
+			}
+			pause.Duration = d
+			rec.Pauses = append(rec.Pauses, pause)
+			paused = false
+			continue
+		}
+		if m := enteringRe.FindStringSubmatch(line); m != nil {
+			ms, err := parseMilestone(session.MilestoneBand, m[3], m[2])
+			if err != nil {
+				fail(err)
+				continue
+			}
+			ms.Band = m[1]
+			rec.Milestones = append(rec.Milestones, ms)
+			continue
+		}
+		if m := reachingRe.FindStringSubmatch(line); m != nil {
+			ms, err := parseMilestone(session.MilestoneTarget, m[1], m[2])
+			if err != nil {
+				fail(err)
+				continue
+			}
+			rec.Milestones = append(rec.Milestones, ms)
+			continue
+		}
+		if m := cueRe.FindStringSubmatch(line); m != nil {
+			c, err := parseCue(m[1], m[2])
+			if err != nil {
+				fail(err)
+				continue
+			}
+			rec.Cues = append(rec.Cues, c)
+			continue
+		}
+		if m := undidRe.FindStringSubmatch(line); m != nil {
+			c, err := p.parseCorrection(session.CorrectionUndo, m[1], m[2], "", m[3])
+			if err != nil {
+				fail(err)
+				continue
+			}
+			rec.Corrections = append(rec.Corrections, c)
+			continue
+		}
+		if m := reopenRe.FindStringSubmatch(line); m != nil {
+			c, err := p.parseCorrection(session.CorrectionReopen, m[1], m[2], "", m[3])
+			if err != nil {
+				fail(err)
+				continue
+			}
+			rec.Corrections = append(rec.Corrections, c)
+			continue
+		}
+		if m := relabelRe.FindStringSubmatch(line); m != nil {
+			c, err := p.parseCorrection(session.CorrectionRelabel, m[1], m[2], m[3], m[4])
+			if err != nil {
+				fail(err)
+				continue
+			}
+			rec.Corrections = append(rec.Corrections, c)
+			continue
+		}
+		if partRe.MatchString(line) {
+			// The starts of the parts are known from the header.
+			continue
+		}
+		if abortedRe.MatchString(line) {
+			rec.EndReason = session.EndAborted
+			continue
+		}
+		if strings.HasPrefix(line, "Summary by ") && strings.HasSuffix(line, ":") {
+			inSummary = true
+			continue
+		}
+		if err := p.parseHeader(rec, line); err != nil {
+			fail(err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return ps, err
+	}
+	if rec.Config.StartBaseHz == 0 && len(rec.Config.Parts) == 0 && len(rec.Captures) != 0 {
+		rec.Config.StartBaseHz = rec.Captures[0].BaseHz
+		rec.Config.EndBaseHz = rec.Captures[len(rec.Captures)-1].BaseHz
+	}
+	return ps, nil
+}
+
+// parseName reads the start and end Hz and the date from the name of a log
+// named by the default template.
+func (p Parser) parseName(rec *session.Record, name string, modTime time.Time) error {
+	m := nameRe.FindStringSubmatch(name)
+	if m == nil {
+		// Logs named by other templates do not carry this information.
+		return nil
+	}
+	var err error
+	if rec.Config.StartHz, err = parseHz(m[1]); err != nil {
+		return err
+	}
+	if rec.Config.EndHz, err = parseHz(m[2]); err != nil {
+		return err
+	}
+	t, err := time.ParseInLocation("Mon 02 Jan 15.04", m[3], time.Local)
+	if err != nil {
+		return err
+	}
+	rec.Stopped = guessYear(t, m[3][:3], modTime)
+	return nil
+}
+
+// guessYear returns t in the latest year, up to the year of ref, in which t
+// falls on weekday.
+func guessYear(t time.Time, weekday string, ref time.Time) time.Time {
+	if ref.IsZero() {
+		ref = time.Now()
+	}
+	for y := ref.Year(); y > ref.Year()-28; y-- {
+		d := t.AddDate(y-t.Year(), 0, 0)
+		if strings.EqualFold(d.Format("Mon"), weekday) {
+			return d
+		}
+	}
+	return t.AddDate(ref.Year()-t.Year(), 0, 0)
+}
+
+func (p Parser) parseHeader(rec *session.Record, line string) error {
+	i := strings.Index(line, ": ")
+	if i == -1 {
+		return errUnknownLine
+	}
+	key, value := line[:i], line[i+2:]
+	c := &rec.Config
+	if strings.HasPrefix(key, "Part ") {
+		return p.parsePart(rec, value)
+	}
+	switch key {
+	case "Playlist":
+		c.Playlist = value
+	case "Preset":
+		c.Preset = value
+	case "Mode":
+		c.Mode = value
+	case "Curve":
+		c.Curve = session.Curve(value)
+		if value == "" {
+			c.Curve = session.CurveLinear
+		}
+	case "TotalTime", "Offset":
+		m := minutesRe.FindStringSubmatch(value)
+		if m == nil {
+			return errors.New("expecting minutes")
+		}
+		min, _ := strconv.ParseFloat(m[1], 64)
+		d := time.Duration(min * float64(time.Minute))
+		if key == "TotalTime" {
+			c.TotalTime = d
+		} else {
+			c.Offset = d
+		}
+	case "Hz", "BaseHz":
+		m := rangeRe.FindStringSubmatch(value)
+		if m == nil {
+			return errors.New("expecting Hz range")
+		}
+		from, err := parseHz(m[1])
+		if err != nil {
+			return err
+		}
+		to, err := parseHz(m[2])
+		if err != nil {
+			return err
+		}
+		if key == "Hz" {
+			c.StartHz, c.EndHz = from, to
+		} else {
+			c.StartBaseHz, c.EndBaseHz = from, to
+		}
+	case "Program":
+		p, err := parseProgram(value)
+		if err != nil {
+			return err
+		}
+		c.Program = p
+	case "Recovered":
+		rec.Recovered = true
+	default:
+		return errUnknownLine
+	}
+	return nil
+}
+
+// parsePart parses the parameters of a playlist part as described by
+// partText, following its number.
+func (p Parser) parsePart(rec *session.Record, value string) error {
+	fields := strings.Split(value, "; ")
+	part := session.Record{}
+	part.Config.Preset = fields[0]
+	var gap time.Duration
+	for _, f := range fields[1:] {
+		if strings.HasPrefix(f, "Gap: ") {
+			m := minutesRe.FindStringSubmatch(f[len("Gap: "):])
+			if m == nil {
+				return errors.New("expecting minutes")
+			}
+			min, _ := strconv.ParseFloat(m[1], 64)
+			gap = time.Duration(min * float64(time.Minute))
+			continue
+		}
+		if err := p.parseHeader(&part, f); err != nil {
+			return fmt.Errorf("part field %q: %v", f, err)
+		}
+	}
+	rec.Config.Parts = append(rec.Config.Parts, session.Part{Gap: gap, Config: part.Config})
+	return nil
+}
+
+func (p Parser) parseCapture(m []string) (session.Capture, error) {
+	var c session.Capture
+	var err error
+	if c.Hz, err = parseHz(m[1]); err != nil {
+		return c, err
+	}
+	c.Band = m[2]
+	if c.BaseHz, err = parseHz(m[3]); err != nil {
+		return c, err
+	}
+	if c.Elapsed, err = parseElapsed(m[6]); err != nil {
+		return c, err
+	}
+	c.Key, c.Label, c.Attributes = p.binding(m[7])
+	if m[8] != "" {
+		end := &session.IntervalEnd{Band: m[10], Auto: m[11] != ""}
+		if end.Elapsed, err = parseElapsed(m[8]); err != nil {
+			return c, err
+		}
+		if end.Hz, err = parseHz(m[9]); err != nil {
+			return c, err
+		}
+		c.Interval, c.End = true, end
+	}
+	if m[12] != "" {
+		seg, err := strconv.Atoi(m[12])
+		if err != nil {
+			return c, err
+		}
+		c.Segment = seg - 1
+	}
+	if m[13] != "" {
+		part, err := strconv.Atoi(m[13])
+		if err != nil {
+			return c, err
+		}
+		c.Part = part - 1
+		if c.End != nil {
+			c.End.Part = c.Part
+		}
+	}
+	return c, nil
+}
+
+// parseCorrection parses a correction of the capture of a label at elapsed
+// time captured, made at elapsed time at. A relabeled capture gets the label
+// to.
+func (p Parser) parseCorrection(kind session.CorrectionKind, captured, label, to, at string) (session.Correction, error) {
+	c := session.Correction{Kind: kind}
+	var err error
+	if c.Capture.Elapsed, err = parseElapsed(captured); err != nil {
+		return c, err
+	}
+	if c.Elapsed, err = parseElapsed(at); err != nil {
+		return c, err
+	}
+	c.Capture.Key, c.Capture.Label, c.Capture.Attributes = p.binding(label)
+	if kind == session.CorrectionRelabel {
+		c.Key, c.Label, c.Attributes = p.binding(to)
+	}
+	return c, nil
+}
+
+// binding returns the key and the attributes of a label, which are found by
+// the label among the Keys.
+func (p Parser) binding(label string) (rune, string, map[string]string) {
+	for _, b := range p.Keys {
+		if strings.EqualFold(b.Label, label) {
+			return b.Rune(), label, b.Attributes
+		}
+	}
+	return 0, label, nil
+}
+
+func parseMilestone(kind session.MilestoneKind, hz, elapsed string) (session.Milestone, error) {
+	m := session.Milestone{Kind: kind}
+	var err error
+	if m.Hz, err = parseHz(hz); err != nil {
+		return m, err
+	}
+	if m.Elapsed, err = parseElapsed(elapsed); err != nil {
+		return m, err
+	}
+	return m, nil
+}
+
+// parseCue parses a cue as described by its event.
+func parseCue(event, elapsed string) (session.Cue, error) {
+	c := session.Cue{Kind: session.CueKind(event)}
+	var err error
+	if c.Elapsed, err = parseElapsed(elapsed); err != nil {
+		return c, err
+	}
+	switch c.Kind {
+	case session.CueCapturing, session.CueInterval, session.CueLastMinute:
+		return c, nil
+	}
+	if band := strings.TrimPrefix(event, "entering "); band != event && band != "" {
+		c.Kind, c.Band = session.CueBand, band
+		return c, nil
+	}
+	return c, fmt.Errorf("unknown cue %q", event)
+}
+
+// parseProgram parses a program as described by programText.
+func parseProgram(s string) (session.Program, error) {
+	var p session.Program
+	for _, part := range strings.Split(s, ", ") {
+		m := segmentRe.FindStringSubmatch(part)
+		if m == nil {
+			return nil, fmt.Errorf("expecting program segment, got %q", part)
+		}
+		min, _ := strconv.ParseFloat(m[1], 64)
+		from, err := parseHz(m[2])
+		if err != nil {
+			return nil, err
+		}
+		to, err := parseHz(m[3])
+		if err != nil {
+			return nil, err
+		}
+		p = append(p, session.Segment{Minutes: min, FromHz: from, ToHz: to})
+	}
+	return p, nil
+}
+
+// parseHz parses Hz written with either a decimal point or comma.
+func parseHz(s string) (float64, error) {
+	return strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+}
+
+// decimals returns the number of decimals of a number.
+func decimals(s string) int {
+	i := strings.IndexAny(s, ".,")
+	if i == -1 {
+		return 0
+	}
+	return len(s) - i - 1
+}
+
+// parseElapsed parses an elapsed time written as mm:ss or mm:ss.mmm.
+func parseElapsed(s string) (time.Duration, error) {
+	s = strings.Replace(s, ",", ".", 1)
+	i := strings.Index(s, ":")
+	if i == -1 {
+		return 0, errors.New("expecting mm:ss")
+	}
+	min, err := strconv.Atoi(s[:i])
+	if err != nil {
+		return 0, err
+	}
+	sec, err := strconv.ParseFloat(s[i+1:], 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(min)*time.Minute + time.Duration(sec*float64(time.Second)+0.5), nil
+}
+
+// clock returns the time hh:mm:ss on the date of day.
+func clock(day time.Time, hhmmss string) time.Time {
+	t, err := time.Parse("15:04:05", hhmmss)
+	if err != nil || day.IsZero() {
+		return time.Time{}
+	}
+	y, mo, d := day.Date()
+	return time.Date(y, mo, d, t.Hour(), t.Minute(), t.Second(), 0, day.Location())
+}
@@ -0,0 +1,316 @@
+package sessionlog
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nstratos/mdt/session"
+)
+
+var testKeys = []session.Binding{
+	{Key: "q", Label: "Visual memory", Attributes: map[string]string{"Modality": "Visual", "Process": "Memory"}},
+	{Key: "a", Label: "Visual imagination", Attributes: map[string]string{"Modality": "Visual", "Process": "Imagination"}},
+	{Key: "w", Label: "Auditory memory", Attributes: map[string]string{"Modality": "Auditory", "Process": "Memory"}},
+	{Key: "d", Label: "Language thought", Attributes: map[string]string{"Modality": "Language", "Process": "Thought"}},
+	{Key: "f", Label: "Floating", Interval: true},
+}
+
+// parseTestdata parses a log of testdata as if it was last modified on
+// modTime.
+func parseTestdata(t *testing.T, name string, modTime time.Time) Parsed {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name+".txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	ps, err := Parser{Keys: testKeys}.Parse(f, name, modTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ps
+}
+
+func TestParseBaseline(t *testing.T) {
+	modTime := time.Date(2024, 6, 1, 12, 0, 0, 0, time.Local)
+	type capture struct {
+		key     rune
+		label   string
+		elapsed time.Duration
+		hz      float64
+		baseHz  float64
+	}
+	tests := []struct {
+		name         string
+		wantStopped  time.Time
+		wantMode     string
+		wantStartHz  float64
+		wantEndHz    float64
+		wantCaptures []capture
+	}{
+		{
+			name:        "15-8 hz Sat 03 Feb 21.15",
+			wantStopped: time.Date(2024, 2, 3, 21, 15, 0, 0, time.Local),
+			wantMode:    session.ModeBinaural,
+			wantStartHz: 15,
+			wantEndHz:   8,
+			wantCaptures: []capture{
+				{'q', "Visual memory", 30 * time.Second, 15, 80},
+				{'w', "Auditory memory", 4*time.Minute + 30*time.Second, 14.52, 80},
+				{'a', "Visual imagination", 29*time.Minute + 5*time.Second, 9.1, 80},
+			},
+		},
+		{
+			name:        "14.5-8 hz sat 03 Feb 21.15 (2)",
+			wantStopped: time.Date(2024, 2, 3, 21, 15, 0, 0, time.Local),
+			wantMode:    session.ModeIsochronic,
+			wantStartHz: 14.5,
+			wantEndHz:   8,
+			wantCaptures: []capture{
+				{'d', "Language thought", 0, 14.5, 100},
+				{'q', "Visual memory", 12*time.Minute + 45*time.Second, 8.25, 100},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps := parseTestdata(t, tt.name, modTime)
+			if len(ps.Errors) != 0 {
+				t.Fatalf("Errors = %v, want none", ps.Errors)
+			}
+			r := ps.Record
+			if ps.Name != tt.name {
+				t.Errorf("Name = %q, want %q", ps.Name, tt.name)
+			}
+			if !r.Stopped.Equal(tt.wantStopped) {
+				t.Errorf("Stopped = %v, want %v", r.Stopped, tt.wantStopped)
+			}
+			if r.Config.Mode != tt.wantMode {
+				t.Errorf("Mode = %q, want %q", r.Config.Mode, tt.wantMode)
+			}
+			if r.Config.StartHz != tt.wantStartHz || r.Config.EndHz != tt.wantEndHz {
+				t.Errorf("Hz = %v-%v, want %v-%v", r.Config.StartHz, r.Config.EndHz, tt.wantStartHz, tt.wantEndHz)
+			}
+			if len(r.Captures) != len(tt.wantCaptures) {
+				t.Fatalf("got %d captures, want %d", len(r.Captures), len(tt.wantCaptures))
+			}
+			for i, want := range tt.wantCaptures {
+				got := r.Captures[i]
+				if got.Key != want.key || got.Label != want.label || got.Elapsed != want.elapsed ||
+					got.Hz != want.hz || got.BaseHz != want.baseHz {
+					t.Errorf("capture %d = %q %q %v %v %v, want %q %q %v %v %v", i,
+						got.Key, got.Label, got.Elapsed, got.Hz, got.BaseHz,
+						want.key, want.label, want.elapsed, want.hz, want.baseHz)
+				}
+				if got.Attributes["Modality"] == "" {
+					t.Errorf("capture %d has no attributes", i)
+				}
+			}
+		})
+	}
+}
+
+func TestParseReportsUnknownLines(t *testing.T) {
+	ps := parseTestdata(t, "15-8 hz Mon 05 Feb 07.30", time.Date(2024, 6, 1, 12, 0, 0, 0, time.Local))
+	wantLines := []int{4, 5, 6}
+	if len(ps.Errors) != len(wantLines) {
+		t.Fatalf("Errors = %v, want lines %v", ps.Errors, wantLines)
+	}
+	for i, line := range wantLines {
+		if ps.Errors[i].Line != line {
+			t.Errorf("error %d on line %d, want %d", i, ps.Errors[i].Line, line)
+		}
+		if ps.Errors[i].Text == "" || ps.Errors[i].Err == nil {
+			t.Errorf("error %d = %+v, want its text and cause", i, ps.Errors[i])
+		}
+	}
+	// The lines around the broken ones are still read.
+	if len(ps.Record.Captures) != 2 {
+		t.Errorf("got %d captures, want 2", len(ps.Record.Captures))
+	}
+}
+
+// testClock is a session.Clock whose time only moves when it is advanced.
+type testClock struct {
+	t time.Time
+}
+
+func (c *testClock) Now() time.Time { return c.t }
+
+func (c *testClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// roundTrip writes the record of a session as a text log and parses it back.
+func roundTrip(t *testing.T, r session.Record) session.Record {
+	t.Helper()
+	var b bytes.Buffer
+	name := "14-8 hz Wed 01 Jan 10.06"
+	if err := WriteText(&b, name, r); err != nil {
+		t.Fatal(err)
+	}
+	ps, err := Parser{Keys: testKeys}.Parse(&b, name, time.Date(2020, 6, 1, 0, 0, 0, 0, time.Local))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ps.Errors) != 0 {
+		t.Fatalf("Errors = %v, want none, log:\n%s", ps.Errors, b.String())
+	}
+	return ps.Record
+}
+
+func round(hz float64) float64 {
+	return math.Round(hz*100) / 100
+}
+
+func compareCaptures(t *testing.T, got, want []session.Capture) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d captures, want %d", len(got), len(want))
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.Key != w.Key || g.Label != w.Label || g.Elapsed != w.Elapsed || g.Hz != round(w.Hz) ||
+			g.BaseHz != round(w.BaseHz) || g.Band != w.Band || g.Segment != w.Segment || g.Part != w.Part {
+			t.Errorf("capture %d = %+v, want %+v", i, g, w)
+		}
+		if g.Interval != w.Interval || (g.End == nil) != (w.End == nil) {
+			t.Errorf("capture %d interval = %v %+v, want %v %+v", i, g.Interval, g.End, w.Interval, w.End)
+			continue
+		}
+		if w.End != nil && (g.End.Elapsed != w.End.Elapsed || g.End.Hz != round(w.End.Hz) ||
+			g.End.Band != w.End.Band || g.End.Auto != w.End.Auto) {
+			t.Errorf("capture %d end = %+v, want %+v", i, *g.End, *w.End)
+		}
+	}
+}
+
+func TestTextRoundTrip(t *testing.T) {
+	c := &testClock{t: time.Date(2020, 1, 1, 10, 0, 0, 0, time.Local)}
+	s := session.New(session.Config{
+		Mode:        session.ModeBinaural,
+		Curve:       session.CurveLinear,
+		TotalTime:   10 * time.Minute,
+		Offset:      30 * time.Second,
+		StartBaseHz: 100,
+		EndBaseHz:   100,
+		Program: session.Program{
+			{Minutes: 2, FromHz: 14, ToHz: 14},
+			{Minutes: 7.5, FromHz: 14, ToHz: 8},
+		},
+		Precision: 2,
+		Keys:      testKeys,
+		Bands:     session.DefaultBands,
+		Cues:      session.Cues{Every: 2, Bands: true},
+	}, c)
+	s.Start()
+	at := func(d time.Duration, key rune) {
+		t.Helper()
+		c.advance(d)
+		if _, err := s.Capture(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	at(time.Minute, 'q')
+	at(30*time.Second, 'f')
+	c.advance(15 * time.Second)
+	s.Pause()
+	c.advance(time.Minute)
+	s.Resume()
+	at(time.Minute+15*time.Second, 'f')
+	at(30*time.Second, 'a')
+	if _, err := s.Relabel('w'); err != nil {
+		t.Fatal(err)
+	}
+	at(30*time.Second+250*time.Millisecond, 'd')
+	if _, err := s.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	at(time.Minute, 'f')
+	at(10*time.Second, 'f')
+	if _, err := s.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	c.advance(time.Minute)
+	s.Stop(session.EndAborted)
+	want := s.Record()
+
+	got := roundTrip(t, want)
+	compareCaptures(t, got.Captures, want.Captures)
+	if got.EndReason != session.EndAborted {
+		t.Errorf("EndReason = %q, want %q", got.EndReason, session.EndAborted)
+	}
+	if len(got.Config.Program) != 2 || got.Config.Mode != session.ModeBinaural {
+		t.Errorf("Config = %+v, want the program and the mode", got.Config)
+	}
+	if len(got.Pauses) != 1 || got.Pauses[0].Elapsed != want.Pauses[0].Elapsed || got.Pauses[0].Duration != time.Minute {
+		t.Errorf("Pauses = %+v, want %+v", got.Pauses, want.Pauses)
+	}
+	wantCues := want.ReachedCues()
+	if len(got.Cues) != len(wantCues) {
+		t.Fatalf("got %d cues, want %d", len(got.Cues), len(wantCues))
+	}
+	// The text log keeps the elapsed times in milliseconds.
+	for i, w := range wantCues {
+		w.Elapsed = w.Elapsed.Truncate(time.Millisecond)
+		if got.Cues[i] != w {
+			t.Errorf("cue %d = %+v, want %+v", i, got.Cues[i], w)
+		}
+	}
+	if len(got.Corrections) != len(want.Corrections) {
+		t.Fatalf("got %d corrections, want %d", len(got.Corrections), len(want.Corrections))
+	}
+	for i, w := range want.Corrections {
+		g := got.Corrections[i]
+		if g.Kind != w.Kind || g.Elapsed.Truncate(time.Millisecond) != w.Elapsed.Truncate(time.Millisecond) ||
+			g.Capture.Elapsed != w.Capture.Elapsed || g.Capture.Label != w.Capture.Label || g.Label != w.Label {
+			t.Errorf("correction %d = %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+func TestTextRoundTripPlaylist(t *testing.T) {
+	c := &testClock{t: time.Date(2020, 1, 1, 10, 0, 0, 0, time.Local)}
+	cfg := session.NewPlaylist("descent", []session.Part{
+		{Config: session.Config{Preset: "settle", Mode: session.ModeBinaural, Curve: session.CurveLinear,
+			TotalTime: 5 * time.Minute, StartHz: 14, EndHz: 10, StartBaseHz: 100, EndBaseHz: 100}},
+		{Gap: time.Minute, Config: session.Config{Preset: "hold", Mode: session.ModeIsochronic, Curve: session.CurveCosine,
+			TotalTime: 6 * time.Minute, Offset: time.Minute, StartBaseHz: 200, EndBaseHz: 150,
+			Program: session.Program{{Minutes: 2, FromHz: 10, ToHz: 6}, {Minutes: 3, FromHz: 6, ToHz: 6}}}},
+	}, session.Config{Precision: 2, Keys: testKeys, Bands: session.DefaultBands})
+	s := session.New(cfg, c)
+	s.Start()
+	for _, step := range []struct {
+		d   time.Duration
+		key rune
+	}{
+		{2 * time.Minute, 'q'},
+		{2 * time.Minute, 'a'},
+		{3*time.Minute + 30*time.Second, 'w'},
+		{2 * time.Minute, 'd'},
+	} {
+		c.advance(step.d)
+		if _, err := s.Capture(step.key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.advance(time.Minute)
+	s.Stop(session.EndStopped)
+	want := s.Record()
+
+	got := roundTrip(t, want)
+	compareCaptures(t, got.Captures, want.Captures)
+	if got.Config.Playlist != "descent" || len(got.Config.Parts) != 2 {
+		t.Fatalf("Config = %+v, want playlist descent with 2 parts", got.Config)
+	}
+	for i, w := range want.Config.Parts {
+		g := got.Config.Parts[i]
+		if g.Gap != w.Gap || g.Config.Preset != w.Config.Preset || g.Config.Mode != w.Config.Mode ||
+			g.Config.Curve != w.Config.Curve || g.Config.TotalTime != w.Config.TotalTime ||
+			g.Config.Offset != w.Config.Offset || len(g.Config.Program) != len(w.Config.Program) {
+			t.Errorf("part %d = %+v, want %+v", i, g, w)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package sessionlog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nstratos/mdt/session"
+)
+
+// Log is a session log read from a directory.
+type Log struct {
+	Path   string
+	Name   string
+	Record session.Record
+	// Errors holds the lines of a text log that could not be parsed.
+	Errors []ParseError
+	// Err is set when the log could not be read at all.
+	Err error
+}
+
+// ReadDir reads the session logs in dir, sorted by name. Session documents
+// are read with ReadJSON and text logs with p. A text log is skipped when a
+// session document of the same name exists, as both describe the same
+// session and the document is the more complete of the two. Logs in other
+// formats are ignored.
+func ReadDir(dir string, p Parser) ([]Log, error) {
+	dir, err := expandHome(dir)
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		dir = "."
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	documents := make(map[string]bool)
+	for _, fi := range files {
+		if !fi.IsDir() && filepath.Ext(fi.Name()) == "."+FormatJSON {
+			documents[strings.TrimSuffix(fi.Name(), "."+FormatJSON)] = true
+		}
+	}
+	var logs []Log
+	for _, fi := range files {
+		if fi.IsDir() {
+			continue
+		}
+		ext := filepath.Ext(fi.Name())
+		name := strings.TrimSuffix(fi.Name(), ext)
+		path := filepath.Join(dir, fi.Name())
+		switch {
+		case ext == "."+FormatJSON:
+			logs = append(logs, readDocument(path, name))
+		case ext == "."+FormatText && !documents[name]:
+			logs = append(logs, readText(p, path, name))
+		}
+	}
+	sort.Slice(logs, func(i, j int) bool { return logs[i].Path < logs[j].Path })
+	return logs, nil
+}
+
+func readDocument(path, name string) Log {
+	l := Log{Path: path, Name: name}
+	f, err := os.Open(path)
+	if err != nil {
+		l.Err = err
+		return l
+	}
+	defer f.Close()
+	d, err := ReadJSON(f)
+	if err != nil {
+		l.Err = err
+		return l
+	}
+	l.Name, l.Record = d.Name, d.Record()
+	return l
+}
+
+func readText(p Parser, path, name string) Log {
+	l := Log{Path: path, Name: name}
+	ps, err := p.ParseFile(path)
+	if err != nil {
+		l.Err = err
+		return l
+	}
+	l.Name, l.Record, l.Errors = ps.Name, ps.Record, ps.Errors
+	return l
+}
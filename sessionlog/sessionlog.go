@@ -0,0 +1,253 @@
+// Package sessionlog writes the logs of meditation sessions and reads them
+// back.
+//
+// Logs are written in an output directory and named after a filename
+// template which can contain the following placeholders:
+//
+//	{start}     the Hz the session starts with
+//	{end}       the Hz the session ends with
+//	{mode}      the mode, e.g. Binaural, of the first part of a playlist
+//	{preset}    the name of the configuration preset or of the playlist
+//	{seq}       a sequence number that makes the name unique, starting from 1
+//	{year}      the year the session ended, e.g. 2017
+//	{month}     the month as a number, e.g. 12
+//	{monthname} the month as a name, e.g. Dec
+//	{day}       the day of the month, e.g. 27
+//	{weekday}   the day of the week, e.g. Wed
+//	{hour}      the hour, e.g. 22
+//	{minute}    the minute, e.g. 09
+//	{second}    the second, e.g. 45
+//
+// The default template names the logs like '15-19 hz Wed 27 Dec 22.09'.
+package sessionlog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/nstratos/mdt/session"
+)
+
+// DefaultTemplate is the filename template that is used if none is set.
+const DefaultTemplate = "{start}-{end} hz {weekday} {day} {monthname} {hour}.{minute}"
+
+// maxSeq limits the search for a unique name.
+const maxSeq = 10000
+
+// The formats that logs can be written in. Each format is also the extension
+// of its files.
+const (
+	// FormatText is the human readable format written by WriteText.
+	FormatText = "txt"
+	// FormatCSV is the spreadsheet friendly format written by WriteCSV.
+	FormatCSV = "csv"
+	// FormatJSON is the session document written by WriteJSON.
+	FormatJSON = "json"
+)
+
+// Formats holds all the supported formats.
+var Formats = []string{FormatText, FormatCSV, FormatJSON}
+
+// writeFunc writes the log of a record named name to w.
+type writeFunc func(w io.Writer, name string, r session.Record) error
+
+func (o Output) writer(format string) writeFunc {
+	switch format {
+	case FormatText:
+		return WriteText
+	case FormatCSV:
+		return WriteCSV
+	case FormatJSON:
+		return func(w io.Writer, name string, r session.Record) error {
+			return WriteJSON(w, NewDocument(name, r, o.Version))
+		}
+	}
+	return nil
+}
+
+// ValidFormats returns an error if formats is empty or contains a format
+// that is not supported.
+func ValidFormats(formats []string) error {
+	if len(formats) == 0 {
+		return errors.New("at least one log format is needed")
+	}
+	for _, f := range formats {
+		if !contains(Formats, f) {
+			return fmt.Errorf("unknown log format %q", f)
+		}
+	}
+	return nil
+}
+
+// Output describes where and how session logs are written.
+type Output struct {
+	Dir      string // defaults to the current directory
+	Template string // defaults to DefaultTemplate
+	Version  string // version of the program, kept in session documents
+}
+
+// Write writes the log of a record in each of the formats to new files in
+// the output directory. All files share the same name, with the format as
+// extension. It returns the paths of the files that were written.
+func (o Output) Write(r session.Record, formats ...string) ([]string, error) {
+	if err := ValidFormats(formats); err != nil {
+		return nil, err
+	}
+	exts := make([]string, len(formats))
+	for i, f := range formats {
+		exts[i] = "." + f
+	}
+	name, err := o.Name(r, exts...)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for i, f := range formats {
+		path := name + exts[i]
+		if err := write(path, filepath.Base(name), r, o.writer(f)); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+func write(path, name string, r session.Record, fn writeFunc) error {
+	f, err := create(path)
+	if err != nil {
+		return err
+	}
+	if err := fn(f, name, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ValidTemplate returns an error if a filename template contains unknown
+// placeholders.
+func ValidTemplate(template string) error {
+	s := expand(template, session.Record{}, 1)
+	if i := strings.IndexAny(s, "{}"); i != -1 {
+		return fmt.Errorf("unknown placeholder in filename template %q", template)
+	}
+	return nil
+}
+
+// expand replaces the placeholders of a template with the values of a
+// record.
+func expand(template string, r session.Record, seq int) string {
+	c := r.Config
+	first, last := c.PartConfig(0), c.PartConfig(c.PartCount()-1)
+	t := r.Stopped
+	preset := c.Preset
+	if preset == "" {
+		preset = c.Playlist
+	}
+	if preset == "" {
+		preset = "default"
+	}
+	return strings.NewReplacer(
+		"{start}", strconv.FormatFloat(first.Segments().StartHz(), 'f', -1, 64),
+		"{end}", strconv.FormatFloat(last.Segments().EndHz(), 'f', -1, 64),
+		"{mode}", first.Mode,
+		"{preset}", preset,
+		"{seq}", strconv.Itoa(seq),
+		"{year}", t.Format("2006"),
+		"{month}", t.Format("01"),
+		"{monthname}", t.Format("Jan"),
+		"{day}", t.Format("02"),
+		"{weekday}", t.Format("Mon"),
+		"{hour}", t.Format("15"),
+		"{minute}", t.Format("04"),
+		"{second}", t.Format("05"),
+	).Replace(template)
+}
+
+// sanitize replaces the characters that are not allowed in filenames on the
+// supported platforms.
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`<>:"/\|?*`, r) || r < ' ' {
+			return '-'
+		}
+		return r
+	}, name)
+}
+
+// Name returns the path, without extension, of the logs of a record. The
+// name is unique for all the extensions exts, none of which exists in the
+// output directory. If the template contains {seq}, the lowest sequence
+// number that makes the name unique is used, otherwise a number in
+// parentheses is appended on collision. The output directory is created if
+// it does not exist.
+func (o Output) Name(r session.Record, exts ...string) (string, error) {
+	template := o.Template
+	if template == "" {
+		template = DefaultTemplate
+	}
+	dir, err := expandHome(o.Dir)
+	if err != nil {
+		return "", err
+	}
+	if dir != "" {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return "", err
+		}
+	}
+	hasSeq := strings.Contains(template, "{seq}")
+	for seq := 1; seq <= maxSeq; seq++ {
+		name := sanitize(expand(template, r, seq))
+		if !hasSeq && seq > 1 {
+			name = fmt.Sprintf("%s (%d)", name, seq)
+		}
+		path := filepath.Join(dir, name)
+		if !exists(path, exts) {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("could not find a unique name for template %q", template)
+}
+
+// expandHome replaces a leading "~" of dir with the home directory of the
+// current user.
+func expandHome(dir string) (string, error) {
+	if !strings.HasPrefix(dir, "~") {
+		return dir, nil
+	}
+	u, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(u.HomeDir, dir[1:]), nil
+}
+
+func exists(path string, exts []string) bool {
+	for _, ext := range exts {
+		if _, err := os.Stat(path + ext); !os.IsNotExist(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// create creates a new file, failing if it already exists, instead of
+// clobbering it.
+func create(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,176 @@
+package sessionlog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nstratos/mdt/session"
+)
+
+func TestValidTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		wantErr  bool
+	}{
+		{DefaultTemplate, false},
+		{"", false},
+		{"{preset} {mode} {seq} {year}-{month}-{day} {second}", false},
+		{"{start}-{end} hz {weekday} {monthname}", false},
+		{"{foo}", true},
+		{"{Start}", true},
+		{"{start", true},
+		{"start}", true},
+		{"{start}-{end} {", true},
+		{"{{start}}", true},
+	}
+	for _, tt := range tests {
+		if err := ValidTemplate(tt.template); (err != nil) != tt.wantErr {
+			t.Errorf("ValidTemplate(%q) err = %v, want error %v", tt.template, err, tt.wantErr)
+		}
+	}
+}
+
+func TestOutputName(t *testing.T) {
+	stopped := time.Date(2020, 1, 1, 10, 30, 45, 0, time.Local)
+	single := session.Record{
+		Config:  session.Config{Mode: session.ModeBinaural, TotalTime: 30 * time.Minute, StartHz: 14, EndHz: 8.5},
+		Stopped: stopped,
+	}
+	preset := single
+	preset.Config.Preset = `deep/slow: "alpha"`
+	playlist := session.Record{
+		Config: session.NewPlaylist("descent", []session.Part{
+			{Config: session.Config{Mode: session.ModeIsochronic, TotalTime: 5 * time.Minute, StartHz: 15, EndHz: 12}},
+			{Config: session.Config{Mode: session.ModeBinaural, TotalTime: 5 * time.Minute, StartHz: 10, EndHz: 7}},
+		}, session.Config{}),
+		Stopped: stopped,
+	}
+	tests := []struct {
+		name     string
+		template string
+		record   session.Record
+		existing []string
+		exts     []string
+		want     string
+	}{
+		{
+			name:   "default",
+			record: single,
+			exts:   []string{".txt"},
+			want:   "14-8.5 hz Wed 01 Jan 10.30",
+		},
+		{
+			name:     "collision",
+			record:   single,
+			existing: []string{"14-8.5 hz Wed 01 Jan 10.30.txt"},
+			exts:     []string{".txt"},
+			want:     "14-8.5 hz Wed 01 Jan 10.30 (2)",
+		},
+		{
+			name:     "collisions",
+			record:   single,
+			existing: []string{"14-8.5 hz Wed 01 Jan 10.30.txt", "14-8.5 hz Wed 01 Jan 10.30 (2).txt"},
+			exts:     []string{".txt"},
+			want:     "14-8.5 hz Wed 01 Jan 10.30 (3)",
+		},
+		{
+			name:     "collision of one of the extensions",
+			record:   single,
+			existing: []string{"14-8.5 hz Wed 01 Jan 10.30.csv"},
+			exts:     []string{".txt", ".csv"},
+			want:     "14-8.5 hz Wed 01 Jan 10.30 (2)",
+		},
+		{
+			name:     "other extension",
+			record:   single,
+			existing: []string{"14-8.5 hz Wed 01 Jan 10.30.json"},
+			exts:     []string{".txt"},
+			want:     "14-8.5 hz Wed 01 Jan 10.30",
+		},
+		{
+			name:     "seq",
+			template: "{preset} {seq}",
+			record:   single,
+			exts:     []string{".txt"},
+			want:     "default 1",
+		},
+		{
+			name:     "seq collisions",
+			template: "{preset} {seq}",
+			record:   single,
+			existing: []string{"default 1.txt", "default 2.json"},
+			exts:     []string{".txt", ".json"},
+			want:     "default 3",
+		},
+		{
+			name:     "time",
+			template: "{year}-{month}-{day} {weekday} {monthname} {hour}.{minute}.{second}",
+			record:   single,
+			exts:     []string{".txt"},
+			want:     "2020-01-01 Wed Jan 10.30.45",
+		},
+		{
+			name:     "sanitized preset",
+			template: "{preset} {mode}",
+			record:   preset,
+			exts:     []string{".txt"},
+			want:     "deep-slow- -alpha- Binaural",
+		},
+		{
+			name:     "playlist",
+			template: "{preset} {mode} {start}-{end}",
+			record:   playlist,
+			exts:     []string{".txt"},
+			want:     "descent Isochronic 15-7",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "logs")
+			if err := os.Mkdir(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range tt.existing {
+				if err := ioutil.WriteFile(filepath.Join(dir, f), nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := Output{Dir: dir, Template: tt.template}.Name(tt.record, tt.exts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := filepath.Join(dir, tt.want); got != want {
+				t.Errorf("Name() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestOutputWrite(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "new", "logs")
+	o := Output{Dir: dir, Template: "{preset}"}
+	r := session.Record{Config: session.Config{TotalTime: time.Minute}}
+	for _, want := range []string{"default", "default (2)"} {
+		paths, err := o.Write(r, FormatText, FormatCSV, FormatJSON)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(paths) != 3 {
+			t.Fatalf("Write() = %v, want a path for each format", paths)
+		}
+		for i, ext := range []string{".txt", ".csv", ".json"} {
+			if p := filepath.Join(dir, want+ext); paths[i] != p {
+				t.Errorf("path %d = %q, want %q", i, paths[i], p)
+			}
+			if _, err := os.Stat(paths[i]); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+	if _, err := o.Write(r); err == nil {
+		t.Error("Write() without formats err = nil, want error")
+	}
+}
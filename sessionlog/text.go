@@ -0,0 +1,231 @@
+package sessionlog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nstratos/mdt/session"
+)
+
+// WriteText writes the log of a record in a human readable text format, with
+// Windows line endings. The log starts with its name, followed by the mode
+// and the rest of the session parameters, or those of each part of a
+// playlist, and then a line for each capture, like:
+//
+//	15.05hz (beta) @ 80.00 base hz, on 04:30.125 Visual memory
+//
+// The line of an interval capture goes on with its end and its duration.
+// It is written using the configuration that the session ran with.
+func WriteText(w io.Writer, name string, r session.Record) error {
+	c := r.Config
+	format := "Mon 02 Jan 15.04"
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "%v\r\n", name)
+	if len(c.Parts) != 0 {
+		fmt.Fprintf(b, "Playlist: %v\r\nTotalTime: %v min\r\n", c.Playlist, c.TotalTime.Minutes())
+		for i := range c.Parts {
+			fmt.Fprintf(b, "%v\r\n", partText(c, i))
+		}
+	} else {
+		if c.Preset != "" {
+			fmt.Fprintf(b, "Preset: %v\r\n", c.Preset)
+		}
+		fmt.Fprintf(b, "Mode: %v\r\nCurve: %v\r\n", c.Mode, c.Curve)
+		fmt.Fprintf(b, "TotalTime: %v min\r\nOffset: %v min\r\n", c.TotalTime.Minutes(), c.Offset.Minutes())
+		if len(c.Program) != 0 {
+			fmt.Fprintf(b, "Program: %v\r\n", programText(c, c.Program))
+		}
+	}
+	if r.Recovered {
+		fmt.Fprintf(b, "Recovered: session started on %v did not finish\r\n", r.Started.Format(format))
+	}
+	// The starts of the parts of a playlist, the milestones, the cues, the
+	// pauses and the corrections are written between the captures, at the
+	// point of the timer they happened.
+	events := textEvents(r)
+	k := 0
+	until := func(e time.Duration) {
+		for ; k < len(events) && events[k].elapsed < e; k++ {
+			fmt.Fprintf(b, "%v\r\n", events[k].text)
+		}
+	}
+	for _, capt := range r.Captures {
+		until(capt.Elapsed)
+		pc := c.PartConfig(capt.Part)
+		base := fmt.Sprintf("%v base hz", c.FormatHz(capt.BaseHz))
+		if pc.Mode == session.ModeBinaural {
+			left, right := capt.Ears()
+			base += fmt.Sprintf(" (%vhz left, %vhz right)", c.FormatHz(left), c.FormatHz(right))
+		}
+		hz := c.FormatHz(capt.Hz) + "hz"
+		if capt.Band != "" {
+			hz += fmt.Sprintf(" (%v)", capt.Band)
+		}
+		line := fmt.Sprintf("%v @ %v, on %v %v",
+			hz, base, session.FormatElapsed(capt.Elapsed), capt.Label)
+		if capt.End != nil {
+			line += " " + intervalText(c, capt)
+		}
+		if len(pc.Program) != 0 {
+			line += fmt.Sprintf(" (segment %d/%d)", capt.Segment+1, len(pc.Program))
+		}
+		if len(c.Parts) != 0 {
+			line += fmt.Sprintf(" (part %d/%d)", capt.Part+1, len(c.Parts))
+		}
+		fmt.Fprintf(b, "%v\r\n", line)
+	}
+	// The rest happened after the last capture.
+	until(r.Config.TotalTime + 1)
+	if r.EndReason == session.EndAborted {
+		fmt.Fprintf(b, "Aborted at %v\r\n", session.FormatElapsed(r.Elapsed()))
+	}
+	for _, d := range c.Dimensions {
+		groups := session.GroupBy(r.Captures, session.ByAttribute(d))
+		if len(groups) == 0 {
+			continue
+		}
+		b.WriteString(summaryText(c, d, groups))
+	}
+	if groups := session.GroupByBand(r.Captures, c.Bands); len(groups) != 0 {
+		b.WriteString(summaryText(c, "Band", groups))
+	}
+	return b.Flush()
+}
+
+// programText returns a description of a frequency program, e.g.
+// '5 min 14.00-14.00 hz, 10 min 14.00-10.00 hz'.
+func programText(c session.Config, p session.Program) string {
+	segments := make([]string, len(p))
+	for i, seg := range p {
+		segments[i] = fmt.Sprintf("%v min %v-%v hz", seg.Minutes, c.FormatHz(seg.FromHz), c.FormatHz(seg.ToHz))
+	}
+	return strings.Join(segments, ", ")
+}
+
+// partText returns the parameters of a part of a playlist as they appear in
+// the header of the log, e.g.
+//
+//	Part 2/3: hold; Gap: 1 min; Mode: Binaural; Curve: Linear; TotalTime: 10 min; Offset: 0 min; Hz: 8.00-8.00; BaseHz: 100.00-100.00
+func partText(c session.Config, i int) string {
+	p := c.PartConfig(i)
+	fields := []string{
+		fmt.Sprintf("Part %d/%d: %v", i+1, len(c.Parts), p.Preset),
+		fmt.Sprintf("Gap: %v min", c.Parts[i].Gap.Minutes()),
+		fmt.Sprintf("Mode: %v", p.Mode),
+		fmt.Sprintf("Curve: %v", p.Curve),
+		fmt.Sprintf("TotalTime: %v min", p.TotalTime.Minutes()),
+		fmt.Sprintf("Offset: %v min", p.Offset.Minutes()),
+		fmt.Sprintf("Hz: %v-%v", p.FormatHz(p.StartHz), p.FormatHz(p.EndHz)),
+		fmt.Sprintf("BaseHz: %v-%v", p.FormatHz(p.StartBaseHz), p.FormatHz(p.EndBaseHz)),
+	}
+	if len(p.Program) != 0 {
+		fields = append(fields, fmt.Sprintf("Program: %v", programText(p, p.Program)))
+	}
+	return strings.Join(fields, "; ")
+}
+
+// intervalText returns the end of an interval capture as it appears in the
+// log after its label, e.g. 'until 05:10.125 @ 14.90hz (beta) for
+// 00:40.000', followed by '(closed at end)' if the session ended before the
+// interval was closed. The duration is that of the times as written, so that
+// it adds up.
+func intervalText(c session.Config, capt session.Capture) string {
+	end := capt.End
+	d := end.Elapsed.Truncate(time.Millisecond) - capt.Elapsed.Truncate(time.Millisecond)
+	hz := c.FormatHz(end.Hz) + "hz"
+	if end.Band != "" {
+		hz += fmt.Sprintf(" (%v)", end.Band)
+	}
+	t := fmt.Sprintf("until %v @ %v for %v", session.FormatElapsed(end.Elapsed), hz, session.FormatElapsed(d))
+	if end.Auto {
+		t += " (closed at end)"
+	}
+	return t
+}
+
+// textEvent is a line of the log, other than a capture, that happened at an
+// elapsed time of the session.
+type textEvent struct {
+	elapsed time.Duration
+	text    string
+}
+
+// textEvents returns the starts of the playlist parts, the milestones and the
+// cues that the session reached, its pauses and the corrections of its
+// captures, in the order they happened.
+func textEvents(r session.Record) []textEvent {
+	var events []textEvent
+	e := r.Elapsed()
+	for i, p := range r.Config.Parts {
+		if start := r.Config.PartStart(i); start <= e {
+			events = append(events, textEvent{start, fmt.Sprintf("Part %d/%d (%v) started on %v",
+				i+1, len(r.Config.Parts), p.Config.Preset, session.FormatElapsed(start))})
+		}
+	}
+	for _, m := range r.Reached() {
+		events = append(events, textEvent{m.Elapsed, milestoneText(r.Config, m)})
+	}
+	for _, c := range r.ReachedCues() {
+		events = append(events, textEvent{c.Elapsed, fmt.Sprintf("Cue (%v) on %v", c.Event(), session.FormatElapsed(c.Elapsed))})
+	}
+	for _, p := range r.Pauses {
+		events = append(events, textEvent{p.Elapsed, pauseText(p)})
+	}
+	for _, c := range r.Corrections {
+		events = append(events, textEvent{c.Elapsed, correctionText(c)})
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].elapsed < events[j].elapsed })
+	return events
+}
+
+// milestoneText returns a milestone as it appears in the log, e.g.
+// 'Entering theta on 12:00.000 (8.00hz)'.
+func milestoneText(c session.Config, m session.Milestone) string {
+	if m.Kind == session.MilestoneBand {
+		return fmt.Sprintf("%v on %v (%vhz)", m.Event(c), session.FormatElapsed(m.Elapsed), c.FormatHz(m.Hz))
+	}
+	return fmt.Sprintf("%v on %v", m.Event(c), session.FormatElapsed(m.Elapsed))
+}
+
+// pauseText returns the pause and resume points as they appear in the log.
+func pauseText(p session.Pause) string {
+	format := "15:04:05"
+	return fmt.Sprintf("Paused on %v (%v)\r\nResumed on %v (%v), paused for %v",
+		session.FormatElapsed(p.Elapsed), p.Start.Format(format),
+		session.FormatElapsed(p.Elapsed), p.Start.Add(p.Duration).Format(format),
+		session.FormatElapsed(p.Duration))
+}
+
+// correctionText returns a correction as it appears in the log, e.g.
+// 'Undid capture of 04:30.125 Auditory memory on 04:35.000', 'Relabeled
+// capture of 04:30.125 Auditory memory as Visual memory on 04:35.000' or
+// 'Reopened interval of 04:30.125 Visual memory on 05:12.000'.
+func correctionText(c session.Correction) string {
+	capt := fmt.Sprintf("%v %v", session.FormatElapsed(c.Capture.Elapsed), c.Capture.Label)
+	switch c.Kind {
+	case session.CorrectionRelabel:
+		return fmt.Sprintf("Relabeled capture of %v as %v on %v", capt, c.Label, session.FormatElapsed(c.Elapsed))
+	case session.CorrectionReopen:
+		return fmt.Sprintf("Reopened interval of %v on %v", capt, session.FormatElapsed(c.Elapsed))
+	}
+	return fmt.Sprintf("Undid capture of %v on %v", capt, session.FormatElapsed(c.Elapsed))
+}
+
+// summaryText returns the statistics of the captures grouped by the values of
+// a dimension as they appear at the end of the log, e.g.
+//
+//	Summary by Process:
+//	  Imagination: 3 occurrences, 10.50-12.00 hz, mean 11.20 hz
+func summaryText(c session.Config, dimension string, groups []session.Group) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Summary by %v:\r\n", dimension)
+	for _, g := range groups {
+		fmt.Fprintf(&b, "  %v: %d occurrences, %v-%v hz, mean %v hz\r\n",
+			g.Value, g.Count, c.FormatHz(g.MinHz), c.FormatHz(g.MaxHz), c.FormatHz(g.MeanHz))
+	}
+	return b.String()
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nstratos/mdt/session"
+	"github.com/nstratos/mdt/sessionlog"
+	"github.com/nstratos/mdt/stats"
+	"github.com/nstratos/mdt/ui"
+)
+
+const dateLayout = "2006-01-02"
+
+// attrFlag collects the attribute values of repeated -attr Name=Value flags.
+type attrFlag map[string]string
+
+func (a attrFlag) String() string {
+	var s []string
+	for name, value := range a {
+		s = append(s, name+"="+value)
+	}
+	return strings.Join(s, ",")
+}
+
+func (a attrFlag) Set(s string) error {
+	i := strings.Index(s, "=")
+	if i <= 0 || i == len(s)-1 {
+		return errors.New("expecting Name=Value")
+	}
+	a[strings.TrimSpace(s[:i])] = strings.TrimSpace(s[i+1:])
+	return nil
+}
+
+// runStats runs the stats subcommand with its arguments and returns the exit
+// code of the program.
+func runStats(args []string) int {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s stats [flags]\n\n", os.Args[0])
+		fmt.Fprintf(fs.Output(), "Prints statistics about the captures of the sessions in a log directory.\n\n")
+		fs.PrintDefaults()
+	}
+	dir := fs.String("dir", "", "log directory (default the configured OutputDir)")
+	from := fs.String("from", "", "only sessions on or after this date (YYYY-MM-DD)")
+	to := fs.String("to", "", "only sessions on or before this date (YYYY-MM-DD)")
+	labels := fs.String("labels", "", "only captures with these comma separated labels")
+	attrs := make(attrFlag)
+	fs.Var(attrs, "attr", "only captures with this attribute value, e.g. Process=Imagination (repeatable)")
+	hz := fs.String("hz", "", "only captures with Hz in this inclusive range, e.g. 10-12")
+	width := fs.Float64("width", 1, "width of the Hz histogram bins")
+	asJSON := fs.Bool("json", false, "print the statistics as JSON")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if err := stat(*dir, *from, *to, *labels, attrs, *hz, *width, *asJSON); err != nil {
+		fmt.Fprintf(os.Stderr, "stats: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func stat(dir, from, to, labels string, attrs map[string]string, hz string, width float64, asJSON bool) error {
+	if width <= 0 {
+		return errors.New("width must be positive")
+	}
+	var f stats.Filter
+	var err error
+	if from != "" {
+		if f.From, err = time.ParseInLocation(dateLayout, from, time.Local); err != nil {
+			return fmt.Errorf("invalid from date: %v", err)
+		}
+	}
+	if to != "" {
+		if f.To, err = time.ParseInLocation(dateLayout, to, time.Local); err != nil {
+			return fmt.Errorf("invalid to date: %v", err)
+		}
+		// The last day is included.
+		f.To = f.To.AddDate(0, 0, 1)
+	}
+	for _, l := range strings.Split(labels, ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			f.Labels = append(f.Labels, l)
+		}
+	}
+	if len(attrs) != 0 {
+		f.Attributes = attrs
+	}
+	if hz != "" {
+		if f.FromHz, f.ToHz, err = parseHzRange(hz); err != nil {
+			return fmt.Errorf("invalid hz range: %v", err)
+		}
+	}
+
+	// The configuration is only read, stats does not create or change it.
+	var c ui.Config
+	if err := c.Read(); err != nil {
+		return err
+	}
+	if dir == "" {
+		dir = c.OutputDir
+	}
+	logs, err := sessionlog.ReadDir(dir, sessionlog.Parser{Keys: c.Keys})
+	if err != nil {
+		return err
+	}
+	var records []session.Record
+	for _, l := range logs {
+		if l.Err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", l.Path, l.Err)
+			continue
+		}
+		for _, e := range l.Errors {
+			fmt.Fprintf(os.Stderr, "%v: %v\n", l.Path, e)
+		}
+		records = append(records, l.Record)
+	}
+
+	rep := stats.Compute(records, f, width, c.Bands, c.Dimensions)
+	if asJSON {
+		return stats.WriteJSON(os.Stdout, rep)
+	}
+	return stats.WriteText(os.Stdout, rep, c.Precision)
+}
+
+// parseHzRange parses a range of Hz written as lo-hi, with either a decimal
+// point or comma.
+func parseHzRange(s string) (lo, hi float64, err error) {
+	i := strings.Index(s, "-")
+	if i == -1 {
+		return 0, 0, errors.New("expecting lo-hi")
+	}
+	if lo, err = strconv.ParseFloat(strings.Replace(s[:i], ",", ".", 1), 64); err != nil {
+		return 0, 0, err
+	}
+	if hi, err = strconv.ParseFloat(strings.Replace(s[i+1:], ",", ".", 1), 64); err != nil {
+		return 0, 0, err
+	}
+	if hi <= 0 || lo > hi {
+		return 0, 0, errors.New("expecting lo-hi with 0 < hi and lo <= hi")
+	}
+	return lo, hi, nil
+}
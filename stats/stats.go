@@ -0,0 +1,246 @@
+// Package stats analyzes the captures of many sessions together, e.g. all
+// the sessions found in a log directory.
+package stats
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nstratos/mdt/session"
+)
+
+// Filter selects the sessions and the captures that are analyzed.
+type Filter struct {
+	// From and To limit the sessions to those that started at or after From
+	// and before To. A zero time leaves that end of the range open.
+	From time.Time
+	To   time.Time
+	// Labels limits the captures to those with one of the labels, matched
+	// case insensitively. If it is empty, all captures are kept.
+	Labels []string
+	// Attributes limits the captures to those with all of the attribute
+	// values, e.g. {"Process": "Imagination"}, matched case insensitively.
+	Attributes map[string]string
+	// FromHz and ToHz limit the captures to those with Hz between them,
+	// inclusive. If ToHz is 0, the Hz are not limited.
+	FromHz float64
+	ToHz   float64
+}
+
+func (f Filter) session(r session.Record) bool {
+	d := Date(r)
+	if !f.From.IsZero() && d.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !d.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+func (f Filter) capture(c session.Capture) bool {
+	if f.ToHz != 0 && (c.Hz < f.FromHz || c.Hz > f.ToHz) {
+		return false
+	}
+	for name, value := range f.Attributes {
+		if !strings.EqualFold(attribute(c, name), value) {
+			return false
+		}
+	}
+	if len(f.Labels) == 0 {
+		return true
+	}
+	for _, l := range f.Labels {
+		if strings.EqualFold(l, c.Label) {
+			return true
+		}
+	}
+	return false
+}
+
+// attribute returns the value of the attribute of a capture whose name
+// matches name case insensitively.
+func attribute(c session.Capture, name string) string {
+	for n, v := range c.Attributes {
+		if strings.EqualFold(n, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+// Date returns the time a session is dated by. Sessions read back from text
+// logs do not know when they started, so the time they stopped is used
+// instead.
+func Date(r session.Record) time.Time {
+	if r.Started.IsZero() {
+		return r.Stopped
+	}
+	return r.Started
+}
+
+// Label holds the statistics of the captures of a label.
+type Label struct {
+	Label    string
+	Count    int
+	MeanHz   float64
+	MedianHz float64
+	MinHz    float64
+	MaxHz    float64
+}
+
+// Bin is a bar of the Hz histogram, counting the captures with Hz at or
+// above FromHz and below ToHz.
+type Bin struct {
+	FromHz float64
+	ToHz   float64
+	Count  int
+	// Labels holds the number of captures of each label in the bin.
+	Labels map[string]int `json:",omitempty"`
+}
+
+// Week holds the statistics of the sessions of a week.
+type Week struct {
+	Start    time.Time // midnight of the Monday the week starts on
+	Sessions int
+	Captures int
+	MeanHz   float64
+	// Labels holds the number of captures of each label in the week.
+	Labels map[string]int
+}
+
+// Dimension holds the statistics of the captures of each value of a
+// dimension, e.g. of each Modality.
+type Dimension struct {
+	Name   string
+	Groups []session.Group
+}
+
+// Report is the result of analyzing sessions.
+type Report struct {
+	Sessions int
+	Captures int
+	Labels   []Label
+	// Dimensions holds the statistics of the captures by the values of each
+	// dimension, in the order of the dimensions.
+	Dimensions []Dimension `json:",omitempty"`
+	// Bands holds the statistics of the captures of each brainwave band, in
+	// the order of the bands.
+	Bands     []session.Group
+	Histogram []Bin
+	Weeks     []Week
+}
+
+// Compute analyzes the sessions of records selected by f. The Hz histogram
+// has bins of width Hz, aligned to multiples of width. Captures are
+// classified in bands again, rather than by the band they were logged with,
+// so that the sessions of older logs and of different band boundaries can be
+// compared. The captures are also grouped by the values of their attributes
+// for each of dimensions. Labels are sorted by name and weeks by time.
+func Compute(records []session.Record, f Filter, width float64, bands session.Bands, dimensions []string) Report {
+	var rep Report
+	var captures []session.Capture
+	labels := make(map[string][]float64)
+	bins := make(map[int]*Bin)
+	weeks := make(map[time.Time]*Week)
+	for _, r := range records {
+		if !f.session(r) {
+			continue
+		}
+		rep.Sessions++
+		start := weekStart(Date(r))
+		w, ok := weeks[start]
+		if !ok {
+			w = &Week{Start: start, Labels: make(map[string]int)}
+			weeks[start] = w
+		}
+		w.Sessions++
+		for _, c := range r.Captures {
+			if !f.capture(c) {
+				continue
+			}
+			rep.Captures++
+			c.Band = bands.Classify(c.Hz)
+			captures = append(captures, c)
+			labels[c.Label] = append(labels[c.Label], c.Hz)
+			i := int(math.Floor(c.Hz / width))
+			b, ok := bins[i]
+			if !ok {
+				b = &Bin{FromHz: float64(i) * width, ToHz: float64(i+1) * width, Labels: make(map[string]int)}
+				bins[i] = b
+			}
+			b.Count++
+			b.Labels[c.Label]++
+			w.Captures++
+			w.MeanHz += (c.Hz - w.MeanHz) / float64(w.Captures)
+			w.Labels[c.Label]++
+		}
+	}
+	for l, hz := range labels {
+		rep.Labels = append(rep.Labels, label(l, hz))
+	}
+	sort.Slice(rep.Labels, func(i, j int) bool { return rep.Labels[i].Label < rep.Labels[j].Label })
+	for _, d := range dimensions {
+		if groups := session.GroupBy(captures, session.ByAttribute(d)); len(groups) != 0 {
+			rep.Dimensions = append(rep.Dimensions, Dimension{Name: d, Groups: groups})
+		}
+	}
+	rep.Bands = session.GroupByBand(captures, bands)
+	rep.Histogram = histogram(bins, width)
+	for _, w := range weeks {
+		rep.Weeks = append(rep.Weeks, *w)
+	}
+	sort.Slice(rep.Weeks, func(i, j int) bool { return rep.Weeks[i].Start.Before(rep.Weeks[j].Start) })
+	return rep
+}
+
+func label(name string, hz []float64) Label {
+	sort.Float64s(hz)
+	l := Label{Label: name, Count: len(hz), MinHz: hz[0], MaxHz: hz[len(hz)-1]}
+	sum := 0.0
+	for _, v := range hz {
+		sum += v
+	}
+	l.MeanHz = sum / float64(len(hz))
+	if n := len(hz); n%2 == 1 {
+		l.MedianHz = hz[n/2]
+	} else {
+		l.MedianHz = (hz[n/2-1] + hz[n/2]) / 2
+	}
+	return l
+}
+
+// histogram returns the bins sorted by Hz, including the empty ones between
+// them.
+func histogram(bins map[int]*Bin, width float64) []Bin {
+	if len(bins) == 0 {
+		return nil
+	}
+	first, last := math.MaxInt32, math.MinInt32
+	for i := range bins {
+		if i < first {
+			first = i
+		}
+		if i > last {
+			last = i
+		}
+	}
+	h := make([]Bin, 0, last-first+1)
+	for i := first; i <= last; i++ {
+		b, ok := bins[i]
+		if !ok {
+			b = &Bin{FromHz: float64(i) * width, ToHz: float64(i+1) * width}
+		}
+		h = append(h, *b)
+	}
+	return h
+}
+
+// weekStart returns midnight of the Monday of the week that t falls in.
+func weekStart(t time.Time) time.Time {
+	days := (int(t.Weekday()) + 6) % 7
+	y, m, d := t.Date()
+	return time.Date(y, m, d-days, 0, 0, 0, 0, t.Location())
+}
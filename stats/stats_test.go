@@ -0,0 +1,85 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nstratos/mdt/session"
+)
+
+func TestComputeFiltersAndDimensions(t *testing.T) {
+	capture := func(label, modality, process string, hz float64) session.Capture {
+		return session.Capture{
+			Label:      label,
+			Attributes: map[string]string{"Modality": modality, "Process": process},
+			Hz:         hz,
+		}
+	}
+	records := []session.Record{
+		{Started: time.Date(2020, 1, 6, 10, 0, 0, 0, time.UTC), Captures: []session.Capture{
+			capture("Visual imagination", "Visual", "Imagination", 11),
+			capture("Visual memory", "Visual", "Memory", 11),
+			capture("Auditory imagination", "Auditory", "Imagination", 10),
+		}},
+		{Started: time.Date(2020, 1, 13, 10, 0, 0, 0, time.UTC), Captures: []session.Capture{
+			capture("Auditory imagination", "Auditory", "Imagination", 12),
+			capture("Auditory imagination", "Auditory", "Imagination", 12.5),
+			capture("Visual imagination", "Visual", "Imagination", 9.9),
+		}},
+	}
+	tests := []struct {
+		name         string
+		filter       Filter
+		wantCaptures int
+		// wantModality holds the count of each modality.
+		wantModality map[string]int
+	}{
+		{
+			name:         "all",
+			wantCaptures: 6,
+			wantModality: map[string]int{"Auditory": 3, "Visual": 3},
+		},
+		{
+			name:         "attribute",
+			filter:       Filter{Attributes: map[string]string{"process": "imagination"}},
+			wantCaptures: 5,
+			wantModality: map[string]int{"Auditory": 3, "Visual": 2},
+		},
+		{
+			name:         "attribute and hz range",
+			filter:       Filter{Attributes: map[string]string{"Process": "Imagination"}, FromHz: 10, ToHz: 12},
+			wantCaptures: 3,
+			wantModality: map[string]int{"Auditory": 2, "Visual": 1},
+		},
+		{
+			name:         "no match",
+			filter:       Filter{Attributes: map[string]string{"Process": "Voice"}},
+			wantModality: map[string]int{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rep := Compute(records, tt.filter, 1, session.DefaultBands, []string{"Modality", "Process"})
+			if rep.Captures != tt.wantCaptures {
+				t.Errorf("Captures = %d, want %d", rep.Captures, tt.wantCaptures)
+			}
+			got := make(map[string]int)
+			for _, d := range rep.Dimensions {
+				if d.Name != "Modality" {
+					continue
+				}
+				for _, g := range d.Groups {
+					got[g.Value] = g.Count
+				}
+			}
+			if len(got) != len(tt.wantModality) {
+				t.Fatalf("Modality groups = %v, want %v", got, tt.wantModality)
+			}
+			for v, n := range tt.wantModality {
+				if got[v] != n {
+					t.Errorf("Modality %v = %d, want %d", v, got[v], n)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// barWidth is the width of the longest bar of the histogram.
+const barWidth = 40
+
+// WriteText writes a report as tables meant for reading, with Hz values
+// formatted with precision decimals.
+func WriteText(w io.Writer, rep Report, precision int) error {
+	hz := func(v float64) string { return strconv.FormatFloat(v, 'f', precision, 64) }
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "%d sessions, %d captures\n", rep.Sessions, rep.Captures)
+	if rep.Captures == 0 {
+		return tw.Flush()
+	}
+
+	fmt.Fprintf(tw, "\nLabel\tCount\tMean hz\tMedian hz\tMin hz\tMax hz\n")
+	for _, l := range rep.Labels {
+		fmt.Fprintf(tw, "%v\t%d\t%v\t%v\t%v\t%v\n", l.Label, l.Count, hz(l.MeanHz), hz(l.MedianHz), hz(l.MinHz), hz(l.MaxHz))
+	}
+
+	for _, d := range rep.Dimensions {
+		fmt.Fprintf(tw, "\n%v\tCount\tMean hz\tMin hz\tMax hz\n", d.Name)
+		for _, g := range d.Groups {
+			fmt.Fprintf(tw, "%v\t%d\t%v\t%v\t%v\n", g.Value, g.Count, hz(g.MeanHz), hz(g.MinHz), hz(g.MaxHz))
+		}
+	}
+
+	fmt.Fprintf(tw, "\nBand\tCount\tMean hz\tMin hz\tMax hz\n")
+	for _, g := range rep.Bands {
+		fmt.Fprintf(tw, "%v\t%d\t%v\t%v\t%v\n", g.Value, g.Count, hz(g.MeanHz), hz(g.MinHz), hz(g.MaxHz))
+	}
+
+	max := 0
+	for _, b := range rep.Histogram {
+		if b.Count > max {
+			max = b.Count
+		}
+	}
+	fmt.Fprintf(tw, "\nHz\tCount\t\n")
+	for _, b := range rep.Histogram {
+		// Bars are rounded up so that no captures go unseen.
+		bar := strings.Repeat("#", (b.Count*barWidth+max-1)/max)
+		fmt.Fprintf(tw, "%v-%v\t%d\t%v\n", hz(b.FromHz), hz(b.ToHz), b.Count, bar)
+	}
+
+	fmt.Fprintf(tw, "\nWeek\tSessions\tCaptures\tMean hz\tLabels\n")
+	for _, wk := range rep.Weeks {
+		mean := "-"
+		if wk.Captures != 0 {
+			mean = hz(wk.MeanHz)
+		}
+		fmt.Fprintf(tw, "%v\t%d\t%d\t%v\t%v\n", wk.Start.Format("2006-01-02"), wk.Sessions, wk.Captures, mean, counts(wk.Labels))
+	}
+	return tw.Flush()
+}
+
+// counts returns the number of captures of each label, sorted by label.
+func counts(labels map[string]int) string {
+	var names []string
+	for l := range labels {
+		names = append(names, l)
+	}
+	sort.Strings(names)
+	s := make([]string, len(names))
+	for i, l := range names {
+		s[i] = fmt.Sprintf("%v %d", l, labels[l])
+	}
+	return strings.Join(s, ", ")
+}
+
+// WriteJSON writes a report as indented JSON.
+func WriteJSON(w io.Writer, rep Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rep)
+}
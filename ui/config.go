@@ -8,9 +8,19 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/nsf/termbox-go"
+	"github.com/nstratos/mdt/session"
+	"github.com/nstratos/mdt/sessionlog"
 )
 
-const maxHz = 999.99
+const (
+	maxHz        = 999.99
+	maxPrecision = 6
+)
 
 // ConfigField is a key of each configuration value. Each input holds a
 // configuration field so it's easier to update the config.
@@ -24,25 +34,127 @@ func (cf ConfigField) Val() string {
 const (
 	configFolder = ".mdt"
 	configFile   = "config.json"
+	journalFile  = "journal.jsonl"
 
-	configMode      ConfigField = "Mode"
-	configTotalTime ConfigField = "TotalTime"
-	configOffset    ConfigField = "Offset"
-	configBaseHz    ConfigField = "BaseHz"
-	configStartHz   ConfigField = "StartHz"
-	configEndHz     ConfigField = "EndHz"
+	configPreset      ConfigField = "Preset"
+	configPlaylist    ConfigField = "Playlist"
+	configMode        ConfigField = "Mode"
+	configCurve       ConfigField = "Curve"
+	configTotalTime   ConfigField = "TotalTime"
+	configOffset      ConfigField = "Offset"
+	configStartBaseHz ConfigField = "StartBaseHz"
+	configEndBaseHz   ConfigField = "EndBaseHz"
+	configStartHz     ConfigField = "StartHz"
+	configEndHz       ConfigField = "EndHz"
 )
 
-var defaultConfig = Config{"Binaural", 30, 5, 100, 15.00, 8.00}
+var defaultConfig = Config{
+	Mode:        session.ModeBinaural,
+	TotalTime:   30,
+	Offset:      5,
+	StartBaseHz: 100,
+	EndBaseHz:   100,
+	StartHz:     15.00,
+	EndHz:       8.00,
+	Precision:   2,
+	Curve:       session.CurveLinear,
+	Keys: []session.Binding{
+		{Key: "q", Label: "Visual memory", Attributes: attributes("Visual", "Memory")},
+		{Key: "a", Label: "Visual imagination", Attributes: attributes("Visual", "Imagination")},
+		{Key: "w", Label: "Auditory memory", Attributes: attributes("Auditory", "Memory")},
+		{Key: "s", Label: "Auditory imagination", Attributes: attributes("Auditory", "Imagination")},
+		{Key: "e", Label: "Language voice", Attributes: attributes("Language", "Voice")},
+		{Key: "d", Label: "Language thought", Attributes: attributes("Language", "Thought")},
+	},
+	Dimensions:       []string{dimensionModality, dimensionProcess},
+	Bands:            session.DefaultBands,
+	FilenameTemplate: sessionlog.DefaultTemplate,
+	Formats:          []string{sessionlog.FormatText},
+}
+
+// The dimensions that the default labels are classified by.
+const (
+	dimensionModality = "Modality"
+	dimensionProcess  = "Process"
+)
+
+func attributes(modality, process string) map[string]string {
+	return map[string]string{dimensionModality: modality, dimensionProcess: process}
+}
+
+// Keys that are used by the program and cannot be bound to labels.
+const (
+	KeyStart   = ' '
+	KeyPause   = 'p'
+	KeyRecover = 'r'
+)
+
+// Keys that correct the last capture of a running session.
+const (
+	KeyUndo    = termbox.KeyCtrlZ
+	KeyRelabel = termbox.KeyCtrlR
+)
+
+// reservedKey returns true if a key is used by the program or by the inputs.
+func reservedKey(r rune) bool {
+	if r == KeyStart || r == KeyPause || r == KeyRecover {
+		return true
+	}
+	return AllowedEntry(termbox.Event{Ch: r})
+}
 
 // Config represents the program's configuration.
 type Config struct {
+	// Preset is the name of the active preset, whose values are copied to
+	// the configuration. It is empty if no preset is active.
+	Preset    string `json:",omitempty"`
 	Mode      string // A = Binaural, B = Isochronic
 	TotalTime int
 	Offset    int
-	BaseHz    float64
-	StartHz   float64
-	EndHz     float64
+	// BaseHz is the constant base Hz of config files written by older
+	// versions. It is replaced by StartBaseHz and EndBaseHz when loaded.
+	BaseHz      float64 `json:",omitempty"`
+	StartBaseHz float64
+	EndBaseHz   float64
+	StartHz     float64
+	EndHz       float64
+	Precision   int // decimal places of the logged Hz values
+	// Program, if set, replaces the linear progression from StartHz to
+	// EndHz with a list of segments that run one after the other.
+	Program session.Program
+	Curve   session.Curve // shape of the progression of the Hz
+	// Keys binds each key that can be captured to its label.
+	Keys []session.Binding
+	// Dimensions lists the attributes that labels can have, e.g. Modality
+	// and Process. Captures are summarized per dimension.
+	Dimensions []string
+	// Bands lists the brainwave bands, each starting at its FromHz, that
+	// captures are classified in.
+	Bands session.Bands
+	// Targets lists Hz whose reaching is announced during a session and
+	// written in its log, along with the crossings of the bands.
+	Targets []float64
+	// Cues selects the points of a session that are announced by ringing
+	// the terminal bell or running CueCommand.
+	Cues session.Cues
+	// CueCommand, if set, is the command with its arguments that is run at
+	// each cue instead of ringing the bell, e.g. to play a chime.
+	CueCommand []string `json:",omitempty"`
+	// OutputDir is the directory that session logs are written to. If it is
+	// empty, they are written to the current directory.
+	OutputDir string
+	// FilenameTemplate is the template of the logs' names. See package
+	// sessionlog for its placeholders.
+	FilenameTemplate string
+	// Formats lists the formats that each session is logged in, e.g. txt
+	// and csv.
+	Formats []string
+	// Playlists lists the playlists, each running several presets one
+	// after the other.
+	Playlists []Playlist `json:",omitempty"`
+	// Playlist is the name of the active playlist. If it is set, sessions
+	// run the playlist instead of the values of the configuration.
+	Playlist string `json:",omitempty"`
 }
 
 // Validate returns an error if the values of the configuration are not valid.
@@ -50,14 +162,89 @@ func (c Config) Validate() error {
 	if c.Offset >= c.TotalTime {
 		return errors.New("Offset must be lower than total time")
 	}
-	if c.BaseHz > maxHz || c.StartHz > maxHz || c.EndHz > maxHz {
+	if c.StartBaseHz > maxHz || c.EndBaseHz > maxHz || c.StartHz > maxHz || c.EndHz > maxHz {
 		return errors.New("Hz value way too high")
 	}
+	for i, seg := range c.Program {
+		if seg.Minutes <= 0 {
+			return fmt.Errorf("Program segment %d must last more than 0 minutes", i+1)
+		}
+		if seg.FromHz > maxHz || seg.ToHz > maxHz {
+			return fmt.Errorf("Program segment %d Hz value way too high", i+1)
+		}
+	}
+	if c.Program.Duration() > time.Duration(c.TotalTime-c.Offset)*time.Minute {
+		return errors.New("Program must not last longer than total time minus offset")
+	}
+	if err := c.Curve.Valid(); err != nil {
+		return err
+	}
+	if c.Precision < 1 || c.Precision > maxPrecision {
+		return fmt.Errorf("Precision must be between 1 and %d", maxPrecision)
+	}
+	if err := c.Bands.Valid(); err != nil {
+		return fmt.Errorf("Invalid bands: %v", err)
+	}
+	for _, t := range c.Targets {
+		if t <= 0 || t > maxHz {
+			return fmt.Errorf("Target %v Hz must be between 0 and %v", t, maxHz)
+		}
+	}
+	if c.Cues.Every < 0 {
+		return errors.New("Cues must not be every negative minutes")
+	}
+	if len(c.CueCommand) != 0 && c.CueCommand[0] == "" {
+		return errors.New("Cue command must start with the program to run")
+	}
+	if err := validatePlaylists(c.Playlists, c.Playlist); err != nil {
+		return err
+	}
+	if err := sessionlog.ValidTemplate(c.FilenameTemplate); err != nil {
+		return err
+	}
+	if err := sessionlog.ValidFormats(c.Formats); err != nil {
+		return err
+	}
+	return validateKeys(c.Keys, c.Dimensions)
+}
+
+func validateKeys(keys []session.Binding, dimensions []string) error {
+	if len(keys) == 0 {
+		return errors.New("At least one key must be bound to a label")
+	}
+	seen := make(map[rune]bool)
+	for _, b := range keys {
+		r := b.Rune()
+		if r == utf8.RuneError || !unicode.IsPrint(r) {
+			return fmt.Errorf("Key %q must be a single printable character", b.Key)
+		}
+		if reservedKey(r) {
+			return fmt.Errorf("Key %q is used by the program", b.Key)
+		}
+		if seen[r] {
+			return fmt.Errorf("Key %q is bound more than once", b.Key)
+		}
+		if b.Label == "" {
+			return fmt.Errorf("Key %q must have a label", b.Key)
+		}
+		for name := range b.Attributes {
+			if !contains(dimensions, name) {
+				return fmt.Errorf("Key %q has attribute %q which is not one of the dimensions", b.Key, name)
+			}
+		}
+		seen[r] = true
+	}
 	return nil
 }
 
-// Save writes the configuration to config.json file.
+// Save writes the configuration to config.json file. The values of the
+// active preset, if any, are saved to its file as well.
 func (c Config) Save() error {
+	if c.Preset != "" {
+		if err := SavePreset(c.Preset, c); err != nil {
+			return err
+		}
+	}
 	return writeConfig(c)
 }
 
@@ -91,6 +278,19 @@ func createConfigFolderIfNotExist() error {
 	return nil
 }
 
+// JournalPath returns the path of the journal file that the running session
+// is written to, inside the configuration folder.
+func JournalPath() (string, error) {
+	u, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	if err := createConfigFolderIfNotExist(); err != nil {
+		return "", err
+	}
+	return filepath.Join(u.HomeDir, configFolder, journalFile), nil
+}
+
 // Load loads configuration from the config.json file.
 func (c *Config) Load() error {
 	u, err := user.Current()
@@ -104,7 +304,22 @@ func (c *Config) Load() error {
 			return err
 		}
 	}
-	b, err := ioutil.ReadFile(configPath)
+	return c.Read()
+}
+
+// Read reads configuration from the config.json file without writing it.
+// If the file does not exist, the configuration has the default values.
+func (c *Config) Read() error {
+	u, err := user.Current()
+	if err != nil {
+		return err
+	}
+	b, err := ioutil.ReadFile(filepath.Join(u.HomeDir, configFolder, configFile))
+	if os.IsNotExist(err) {
+		*c = defaultConfig
+		c.setDefaults()
+		return nil
+	}
 	if err != nil {
 		return err
 	}
@@ -114,9 +329,46 @@ func (c *Config) Load() error {
 	if err != nil {
 		return fmt.Errorf("loading config error: %v", err)
 	}
+	c.setDefaults()
 	return nil
 }
 
+// setDefaults sets the default value to the fields that are missing from
+// config files written by older versions.
+func (c *Config) setDefaults() {
+	if c.Precision == 0 {
+		c.Precision = defaultConfig.Precision
+	}
+	if c.StartBaseHz == 0 {
+		c.StartBaseHz = c.BaseHz
+		if c.StartBaseHz == 0 {
+			c.StartBaseHz = defaultConfig.StartBaseHz
+		}
+	}
+	if c.EndBaseHz == 0 {
+		c.EndBaseHz = c.StartBaseHz
+	}
+	c.BaseHz = 0
+	if c.Curve == "" {
+		c.Curve = defaultConfig.Curve
+	}
+	if len(c.Keys) == 0 {
+		c.Keys = defaultConfig.Keys
+	}
+	if len(c.Dimensions) == 0 {
+		c.Dimensions = defaultConfig.Dimensions
+	}
+	if len(c.Bands) == 0 {
+		c.Bands = defaultConfig.Bands
+	}
+	if c.FilenameTemplate == "" {
+		c.FilenameTemplate = defaultConfig.FilenameTemplate
+	}
+	if len(c.Formats) == 0 {
+		c.Formats = defaultConfig.Formats
+	}
+}
+
 // Update updates the configuration values be accepting a map of these values
 // using the key of each configuration field.
 func (c *Config) Update(m map[string]interface{}) error {
@@ -137,11 +389,97 @@ func UpdateConfig(c Config) {
 	config = c
 }
 
+// Session returns the configuration that a session runs with.
+func (c Config) Session() session.Config {
+	return session.Config{
+		Preset:      c.Preset,
+		Mode:        c.Mode,
+		TotalTime:   time.Duration(c.TotalTime) * time.Minute,
+		Offset:      time.Duration(c.Offset) * time.Minute,
+		StartBaseHz: c.StartBaseHz,
+		EndBaseHz:   c.EndBaseHz,
+		StartHz:     c.StartHz,
+		EndHz:       c.EndHz,
+		Program:     c.Program,
+		Curve:       c.Curve,
+		Precision:   c.Precision,
+		Keys:        c.Keys,
+		Dimensions:  c.Dimensions,
+		Bands:       c.Bands,
+		Targets:     c.Targets,
+		Cues:        c.Cues,
+	}
+}
+
+// LabelKey returns true if a key is bound to a label.
+func LabelKey(key rune) bool {
+	_, ok := config.Session().Binding(key)
+	return ok
+}
+
+// FieldS returns a string representation of the value of a configuration
+// field.
+func (c Config) FieldS(cf ConfigField) string {
+	switch cf {
+	case configPreset:
+		return c.PresetS()
+	case configPlaylist:
+		return c.PlaylistS()
+	case configMode:
+		return c.ModeS()
+	case configCurve:
+		return c.CurveS()
+	case configTotalTime:
+		return c.TotalTimeS()
+	case configOffset:
+		return c.OffsetS()
+	case configStartBaseHz:
+		return c.StartBaseHzS()
+	case configEndBaseHz:
+		return c.EndBaseHzS()
+	case configStartHz:
+		return c.StartHzS()
+	case configEndHz:
+		return c.EndHzS()
+	}
+	return ""
+}
+
+// PresetS returns a string representation of the active preset, shortened
+// to fit in its input.
+func (c Config) PresetS() string {
+	return shorten(c.Preset)
+}
+
+// shorten returns a name shortened to fit in an input, or "none" if it is
+// empty.
+func shorten(name string) string {
+	r := []rune(name)
+	switch {
+	case len(r) == 0:
+		return noPreset
+	case len(r) > inputWidth:
+		return string(r[:inputWidth-1]) + "~"
+	}
+	return name
+}
+
+// PlaylistS returns a string representation of the active playlist,
+// shortened to fit in its input.
+func (c Config) PlaylistS() string {
+	return shorten(c.Playlist)
+}
+
 // ModeS returns a string representation of the mode.
 func (c Config) ModeS() string {
 	return c.Mode
 }
 
+// CurveS returns a string representation of the curve.
+func (c Config) CurveS() string {
+	return string(c.Curve)
+}
+
 // TotalTimeS returns a string representation of the total time.
 func (c Config) TotalTimeS() string {
 	return fmt.Sprintf("%v min", c.TotalTime)
@@ -152,9 +490,14 @@ func (c Config) OffsetS() string {
 	return fmt.Sprintf("%v min", c.Offset)
 }
 
-// BaseHzS returns a string representation of the base hz.
-func (c Config) BaseHzS() string {
-	return fmt.Sprintf("%.2f hz", c.BaseHz)
+// StartBaseHzS returns a string representation of the start base hz.
+func (c Config) StartBaseHzS() string {
+	return fmt.Sprintf("%.2f hz", c.StartBaseHz)
+}
+
+// EndBaseHzS returns a string representation of the end base hz.
+func (c Config) EndBaseHzS() string {
+	return fmt.Sprintf("%.2f hz", c.EndBaseHz)
 }
 
 // StartHzS returns a string representation of the start hz.
@@ -166,3 +509,12 @@ func (c Config) StartHzS() string {
 func (c Config) EndHzS() string {
 	return fmt.Sprintf("%.2f hz", c.EndHz)
 }
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package ui
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/nstratos/mdt/session"
+)
+
+func TestReservedKey(t *testing.T) {
+	reserved := " pr0123456789.,"
+	for _, r := range reserved {
+		if !reservedKey(r) {
+			t.Errorf("reservedKey(%q) = false, want true", r)
+		}
+	}
+	for _, r := range "qawsedfP;-éΩ" {
+		if reservedKey(r) {
+			t.Errorf("reservedKey(%q) = true, want false", r)
+		}
+	}
+}
+
+func TestValidateKeys(t *testing.T) {
+	dimensions := []string{"Modality", "Process"}
+	tests := []struct {
+		name    string
+		keys    []session.Binding
+		wantErr string
+	}{
+		{
+			name: "valid",
+			keys: []session.Binding{
+				{Key: "q", Label: "Visual memory", Attributes: map[string]string{"Modality": "Visual"}},
+				{Key: "é", Label: "Floating", Interval: true},
+			},
+		},
+		{
+			name:    "none",
+			wantErr: "At least one key must be bound to a label",
+		},
+		{
+			name:    "duplicate",
+			keys:    []session.Binding{{Key: "q", Label: "Visual memory"}, {Key: "q", Label: "Auditory memory"}},
+			wantErr: `Key "q" is bound more than once`,
+		},
+		{
+			name:    "start key",
+			keys:    []session.Binding{{Key: " ", Label: "Space"}},
+			wantErr: `Key " " is used by the program`,
+		},
+		{
+			name:    "pause key",
+			keys:    []session.Binding{{Key: "p", Label: "Pause"}},
+			wantErr: `Key "p" is used by the program`,
+		},
+		{
+			name:    "digit",
+			keys:    []session.Binding{{Key: "1", Label: "One"}},
+			wantErr: `Key "1" is used by the program`,
+		},
+		{
+			name:    "decimal comma",
+			keys:    []session.Binding{{Key: ",", Label: "Comma"}},
+			wantErr: `Key "," is used by the program`,
+		},
+		{
+			name:    "empty label",
+			keys:    []session.Binding{{Key: "q"}},
+			wantErr: `Key "q" must have a label`,
+		},
+		{
+			name:    "more than one character",
+			keys:    []session.Binding{{Key: "qq", Label: "Visual memory"}},
+			wantErr: `Key "qq" must be a single printable character`,
+		},
+		{
+			name:    "not printable",
+			keys:    []session.Binding{{Key: "\t", Label: "Tab"}},
+			wantErr: `Key "\t" must be a single printable character`,
+		},
+		{
+			name:    "unknown dimension",
+			keys:    []session.Binding{{Key: "q", Label: "Visual memory", Attributes: map[string]string{"Mood": "Calm"}}},
+			wantErr: `Key "q" has attribute "Mood" which is not one of the dimensions`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateKeys(tt.keys, dimensions)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateKeys() err = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("validateKeys() err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestValidateOlderConfigWithComma checks that a config file written before
+// ',' became a decimal comma, which binds it to a label, is no longer valid.
+func TestValidateOlderConfigWithComma(t *testing.T) {
+	old := `{
+  "Mode": "Binaural",
+  "TotalTime": 30,
+  "Offset": 5,
+  "BaseHz": 100,
+  "StartHz": 15,
+  "EndHz": 8,
+  "Keys": [
+    {"Key": "q", "Label": "Visual memory"},
+    {"Key": ",", "Label": "Language thought"}
+  ]
+}`
+	var c Config
+	if err := json.Unmarshal([]byte(old), &c); err != nil {
+		t.Fatal(err)
+	}
+	c.setDefaults()
+	err := c.Validate()
+	if err == nil || !strings.Contains(err.Error(), `","`) {
+		t.Fatalf("Validate() err = %v, want the ',' key to be rejected", err)
+	}
+	// Binding another key instead makes it valid again.
+	c.Keys[1].Key = "d"
+	if err := c.Validate(); err != nil {
+		t.Errorf("Validate() err = %v after rebinding ',', want nil", err)
+	}
+}
@@ -0,0 +1,34 @@
+package ui
+
+import (
+	"os"
+	"os/exec"
+
+	"github.com/nstratos/mdt/session"
+)
+
+// Cue announces a cue of a session. It rings the terminal bell or, if a cue
+// command is configured, it runs the command without waiting for it to
+// finish. The command gets the event of the cue, e.g. 'last minute', in the
+// MDT_CUE environment variable.
+func Cue(cue session.Cue) error {
+	command := GetConfig().CueCommand
+	if len(command) == 0 {
+		return bell()
+	}
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Env = append(os.Environ(), "MDT_CUE="+cue.Event())
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// bell rings the terminal bell.
+func bell() error {
+	mu.Lock()
+	defer mu.Unlock()
+	_, err := os.Stdout.WriteString("\a")
+	return err
+}
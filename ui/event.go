@@ -0,0 +1,7 @@
+package ui
+
+import "github.com/nsf/termbox-go"
+
+// KeyShiftTab is the key of Shift-Tab events returned by PollEvent. termbox
+// does not know this key, so it is given a value that none of its keys use.
+const KeyShiftTab termbox.Key = 0xFF00
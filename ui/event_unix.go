@@ -0,0 +1,169 @@
+//go:build !windows
+// +build !windows
+
+package ui
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nsf/termbox-go"
+)
+
+var (
+	// shiftTab is the escape sequence that terminals send for Shift-Tab.
+	shiftTab = []byte("\x1b[Z")
+	// Terminals in bracketed paste mode wrap pasted text in these escape
+	// sequences.
+	pasteStart = []byte("\x1b[200~")
+	pasteEnd   = []byte("\x1b[201~")
+)
+
+// enablePaste turns bracketed paste mode on so that pasted text can be told
+// apart from typed keys.
+func enablePaste() {
+	fmt.Fprint(os.Stdout, "\x1b[?2004h")
+}
+
+// disablePaste turns bracketed paste mode off.
+func disablePaste() {
+	fmt.Fprint(os.Stdout, "\x1b[?2004l")
+}
+
+// escWait is how long PollEvent waits for the rest of an escape sequence
+// that arrives split before it takes a lone Esc for the Esc key. termbox
+// waits as long on macOS.
+const escWait = 100 * time.Millisecond
+
+// rawEvent is an event of termbox.PollRawEvent along with its raw input.
+type rawEvent struct {
+	ev   termbox.Event
+	data []byte
+}
+
+var (
+	// raw holds the input that has not been parsed into events yet.
+	raw []byte
+	// pending holds the events that have been parsed but not returned yet.
+	pending []termbox.Event
+	// rawEvents receives the events of termbox.PollRawEvent once polling
+	// starts.
+	rawEvents = make(chan rawEvent)
+	polling   sync.Once
+)
+
+// PollEvent waits for an event and returns it, like termbox.PollEvent. It
+// also recognizes the Shift-Tab key, which termbox would otherwise report as
+// an Esc followed by two characters, and pasted text, which it returns as a
+// key event for each of its characters that an input accepts. Other pasted
+// characters are dropped so that pasting cannot capture label keys.
+func PollEvent() termbox.Event {
+	polling.Do(func() { go pollRaw(rawEvents) })
+	return nextEvent(rawEvents)
+}
+
+// pollRaw sends the raw events of termbox to events for as long as the
+// program runs.
+func pollRaw(events chan<- rawEvent) {
+	for {
+		data := make([]byte, 256)
+		ev := termbox.PollRawEvent(data)
+		events <- rawEvent{ev: ev, data: data[:ev.N]}
+	}
+}
+
+// nextEvent returns the next event parsed from the raw events. While the raw
+// input ends in an incomplete escape sequence it waits escWait for the rest
+// of it, after which the input is parsed as it is.
+func nextEvent(events <-chan rawEvent) termbox.Event {
+	for len(pending) == 0 {
+		var timeout <-chan time.Time
+		if incomplete(raw) {
+			t := time.NewTimer(escWait)
+			defer t.Stop()
+			timeout = t.C
+		}
+		select {
+		case re := <-events:
+			if re.ev.Type != termbox.EventRaw {
+				return re.ev
+			}
+			raw = append(raw, re.data...)
+			parseRaw(false)
+		case <-timeout:
+			parseRaw(true)
+		}
+	}
+	ev := pending[0]
+	pending = pending[1:]
+	return ev
+}
+
+// incomplete returns true if b starts with an escape sequence that has not
+// been received whole yet: a lone Esc, a CSI sequence without its final byte
+// or an SS3 sequence without its key.
+func incomplete(b []byte) bool {
+	if len(b) == 0 || b[0] != '\x1b' {
+		return false
+	}
+	if len(b) == 1 {
+		return true
+	}
+	switch b[1] {
+	case 'O':
+		return len(b) == 2
+	case '[':
+		// X10 mouse sequences are followed by three bytes.
+		if len(b) > 2 && b[2] == 'M' {
+			return len(b) < 6
+		}
+		for _, c := range b[2:] {
+			if c >= 0x40 && c <= 0x7e {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// parseRaw parses the raw input into pending events. An incomplete event is
+// left in the raw input until the rest of it arrives, unless flush is true,
+// in which case an incomplete escape sequence is parsed as it is.
+func parseRaw(flush bool) {
+	for len(raw) != 0 {
+		if bytes.HasPrefix(raw, shiftTab) {
+			pending = append(pending, termbox.Event{Type: termbox.EventKey, Key: KeyShiftTab})
+			raw = raw[len(shiftTab):]
+			continue
+		}
+		if bytes.HasPrefix(raw, pasteStart) {
+			end := bytes.Index(raw, pasteEnd)
+			if end == -1 {
+				return
+			}
+			for _, r := range string(raw[len(pasteStart):end]) {
+				ev := termbox.Event{Type: termbox.EventKey, Ch: r}
+				if AllowedEntry(ev) {
+					pending = append(pending, ev)
+				}
+			}
+			raw = raw[end+len(pasteEnd):]
+			continue
+		}
+		if !flush && incomplete(raw) {
+			return
+		}
+		ev := termbox.ParseEvent(raw)
+		if ev.N == 0 {
+			return
+		}
+		if ev.Type != termbox.EventNone {
+			pending = append(pending, ev)
+		}
+		raw = raw[ev.N:]
+	}
+}
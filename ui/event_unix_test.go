@@ -0,0 +1,113 @@
+//go:build !windows
+// +build !windows
+
+package ui
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nsf/termbox-go"
+)
+
+func TestNextEventSplitSequences(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+		// pause is the time between the chunks.
+		pause time.Duration
+		want  []termbox.Event
+	}{
+		{
+			name:   "shift-tab",
+			chunks: []string{"\x1b", "[", "Z"},
+			pause:  escWait / 4,
+			want:   []termbox.Event{{Type: termbox.EventKey, Key: KeyShiftTab}},
+		},
+		{
+			name:   "paste",
+			chunks: []string{"\x1b[20", "0~1,5q\x1b[2", "01~"},
+			pause:  escWait / 4,
+			want: []termbox.Event{
+				{Type: termbox.EventKey, Ch: '1'},
+				{Type: termbox.EventKey, Ch: ','},
+				{Type: termbox.EventKey, Ch: '5'},
+			},
+		},
+		{
+			name:   "mouse",
+			chunks: []string{"\x1b[<0;1", "0;5", "M"},
+			pause:  escWait / 4,
+			want:   []termbox.Event{{Type: termbox.EventMouse, Key: termbox.MouseLeft, MouseX: 9, MouseY: 4, N: 10}},
+		},
+		{
+			name:   "esc",
+			chunks: []string{"\x1b"},
+			want:   []termbox.Event{{Type: termbox.EventKey, Key: termbox.KeyEsc, N: 1}},
+		},
+		{
+			name:   "esc and a late key",
+			chunks: []string{"\x1b", "q"},
+			pause:  2 * escWait,
+			want: []termbox.Event{
+				{Type: termbox.EventKey, Key: termbox.KeyEsc, N: 1},
+				{Type: termbox.EventKey, Ch: 'q', N: 1},
+			},
+		},
+		{
+			name:   "esc twice",
+			chunks: []string{"\x1b\x1b"},
+			want: []termbox.Event{
+				{Type: termbox.EventKey, Key: termbox.KeyEsc, N: 1},
+				{Type: termbox.EventKey, Key: termbox.KeyEsc, N: 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, pending = nil, nil
+			events := make(chan rawEvent)
+			go func() {
+				for i, c := range tt.chunks {
+					if i > 0 {
+						time.Sleep(tt.pause)
+					}
+					events <- rawEvent{ev: termbox.Event{Type: termbox.EventRaw, N: len(c)}, data: []byte(c)}
+				}
+			}()
+			for i, want := range tt.want {
+				if got := nextEvent(events); got != want {
+					t.Fatalf("event %d = %+v, want %+v", i, got, want)
+				}
+			}
+			if len(raw) != 0 || len(pending) != 0 {
+				t.Errorf("left raw %q and pending %v, want none", raw, pending)
+			}
+		})
+	}
+}
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"", false},
+		{"q", false},
+		{"\x1b", true},
+		{"\x1bq", false},
+		{"\x1bO", true},
+		{"\x1bOA", false},
+		{"\x1b[", true},
+		{"\x1b[1;5", true},
+		{"\x1b[1;5A", false},
+		{"\x1b[200~", false},
+		{"\x1b[M ", true},
+		{"\x1b[M !!", false},
+	}
+	for _, tt := range tests {
+		if got := incomplete([]byte(tt.in)); got != tt.want {
+			t.Errorf("incomplete(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package ui
+
+import "github.com/nsf/termbox-go"
+
+// enablePaste does nothing as the Windows console does not support bracketed
+// paste mode.
+func enablePaste() {}
+
+func disablePaste() {}
+
+// PollEvent waits for an event and returns it. On Windows it is the same as
+// termbox.PollEvent, which does not report Shift-Tab.
+func PollEvent() termbox.Event {
+	return termbox.PollEvent()
+}
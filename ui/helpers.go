@@ -6,31 +6,37 @@ import (
 	"strings"
 
 	"github.com/nsf/termbox-go"
+	"github.com/nstratos/mdt/session"
 )
 
-// CurrentHz given the seconds passed, it returns the current Gz based on the formula:
-// CurrentHz = ( Seconds * Abs((EndHz - StartHz)) / ((TotalTime - Offset) * 60) ) + StartHz
-// for Offset < TotalTime
-// func CurrentHz(seconds int) float64 {
-// 	offset := config.Offset
-// 	if offset >= config.TotalTime {
-// 		offset = 0
-// 	}
-// 	return (float64(seconds) * (math.Abs(config.EndHz - config.StartHz)) / float64((config.TotalTime-offset)*60)) + config.StartHz
-// }
-func CurrentHz(currentSecs int) float64 {
-
-	hzPerSecond := (config.EndHz - config.StartHz) / (float64((config.TotalTime - config.Offset) * 60))
-	secondsSinceOffset := float64(currentSecs - (config.Offset * 60))
-
-	currentHz := hzPerSecond*secondsSinceOffset + config.StartHz
+// RecordedKeyText returns a message indicating the key pressed, it's hz value and band and a timestamp of when it was received.
+// The hz value is presented as configured for the session sc.
+func RecordedKeyText(c session.Capture, sc session.Config) string {
+	hz := sc.FormatHz(c.Hz) + "hz"
+	if c.Band != "" {
+		hz += " " + c.Band
+	}
+	return fmt.Sprintf("Recorded %v (%v) on %v \"%v\"", strconv.QuoteRune(c.Key), hz, session.FormatElapsed(c.Elapsed), c.Label)
+}
 
-	return currentHz
+// IntervalText returns a message indicating the key pressed that opened or
+// closed an interval capture. For a closed interval it shows the hz value and
+// the band of its end and how long it lasted.
+func IntervalText(c session.Capture, sc session.Config) string {
+	if c.End == nil {
+		return "Opened" + strings.TrimPrefix(RecordedKeyText(c, sc), "Recorded")
+	}
+	hz := sc.FormatHz(c.End.Hz) + "hz"
+	if c.End.Band != "" {
+		hz += " " + c.End.Band
+	}
+	return fmt.Sprintf("Closed %v (%v) \"%v\" after %v", strconv.QuoteRune(c.Key), hz, c.Label, session.FormatElapsed(c.Duration()))
 }
 
-// RecordedKeyText returns a message indicating the key pressed, it's hz value and a timestamp of when it was received.
-func RecordedKeyText(key rune, seconds int) string {
-	return fmt.Sprintf("Recorded %v (%.2fhz) on %v \"%v\"", strconv.QuoteRune(key), CurrentHz(seconds), FormatTimer(seconds), Labels[key])
+// CaptureText returns a short description of a capture, with its key, its
+// timestamp and its label.
+func CaptureText(c session.Capture) string {
+	return fmt.Sprintf("%v on %v \"%v\"", strconv.QuoteRune(c.Key), session.FormatElapsed(c.Elapsed), c.Label)
 }
 
 func text(x, y int, s string) (maxX, maxY int) {
@@ -76,6 +82,19 @@ func tbfill(x, y, w, h int, cell termbox.Cell) {
 	mu.Unlock()
 }
 
+// reverse redraws w cells of the screen, starting from x, y, in reverse
+// colors.
+func reverse(x, y, w int) {
+	mu.Lock()
+	mx, my := termbox.Size()
+	cb := termbox.CellBuffer()
+	attr := termbox.ColorDefault | termbox.AttrReverse
+	for i := x; i < x+w && i < mx && y < my; i++ {
+		termbox.SetCell(i, y, cb[y*mx+i].Ch, attr, attr)
+	}
+	mu.Unlock()
+}
+
 func fill(x, y, w, h int, r rune) {
 	tbfill(x, y, w, h, termbox.Cell{Ch: r})
 }
@@ -4,8 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/nsf/termbox-go"
+	"github.com/nstratos/mdt/session"
 )
 
 // InputType is the type of each input which can be either an input that
@@ -37,13 +39,15 @@ type Input struct {
 	Field  ConfigField
 }
 
-// buffer containing the runes of each cell and a cursor.
+// buffer containing the runes of each cell, a cursor and whether all the
+// runes are selected.
 type b struct {
 	buf []rune
 	cur *cur
+	all bool
 }
 
-// cursor of an input.
+// cursor of an input. It is placed before the rune at index i of the buffer.
 type cur struct {
 	i int
 	x int
@@ -62,26 +66,63 @@ func (in *Input) ClearBuf() {
 	in.b = in.newBuf()
 }
 
-// SetBuf sets the input's buffer.
+// SetBuf edits the input's buffer with an entry and shows it. A character is
+// inserted at the cursor, replacing the whole text if it is selected.
 func (in *Input) SetBuf(e *Entry) {
 	//Debug(e.String())
-	if e.Ch != 0 {
-		in.bufAppend(e.Ch)
-	}
-	if e.Backspace {
+	in.bufEdit(e)
+	setCursor(in.cur.x, in.cur.y)
+	in.bufShow()
+}
+
+// bufEdit applies an entry to the buffer and its cursor without drawing
+// them.
+func (in *Input) bufEdit(e *Entry) {
+	switch {
+	case e.Ch != 0:
+		in.bufInsert(e.Ch)
+	case e.Backspace:
 		in.bufBackspace()
+	case e.Delete:
+		in.bufDelete()
+	case e.Left:
+		in.bufMove(in.cur.i - 1)
+	case e.Right:
+		in.bufMove(in.cur.i + 1)
+	case e.Home:
+		in.bufMove(0)
+	case e.End:
+		in.bufMove(len(in.buf))
+	case e.SelectAll:
+		in.all = len(in.buf) != 0
+	case e.Clear:
+		in.bufClear()
 	}
-	in.bufShow()
-	flush()
 }
 
-// Switch switches the input between "Binaural" and "Isochronic" values.
+// Switch switches the input to its next value. The Mode input switches
+// between "Binaural" and "Isochronic" values, the Curve input cycles
+// through the supported curves, the Preset input through the saved presets
+// and the Playlist input through the playlists.
 func (in *Input) Switch() error {
 	c := GetConfig()
-	if c.Mode == "Binaural" {
-		c.Mode = "Isochronic"
-	} else {
-		c.Mode = "Binaural"
+	switch in.Field {
+	case configPreset:
+		name, err := nextPreset(c.Preset)
+		if err != nil {
+			return err
+		}
+		return SelectPreset(name)
+	case configPlaylist:
+		c.Playlist = nextPlaylist(c.Playlists, c.Playlist)
+	case configMode:
+		if c.Mode == session.ModeBinaural {
+			c.Mode = session.ModeIsochronic
+		} else {
+			c.Mode = session.ModeBinaural
+		}
+	case configCurve:
+		c.Curve = c.Curve.Next()
 	}
 	if err := c.Save(); err != nil {
 		return err
@@ -89,6 +130,9 @@ func (in *Input) Switch() error {
 	UpdateConfig(c)
 	UpdateConfig(c)
 	ReloadInputs(c)
+	if in.Field == configPlaylist {
+		UpdateText(c.PlaylistText())
+	}
 	return nil
 }
 
@@ -101,12 +145,12 @@ func (in *Input) ValueMap() (map[string]interface{}, error) {
 	var val interface{}
 	var err error
 	if in.Type == InputNumericInt {
-		if val, err = strconv.Atoi(string(in.buf)); err != nil {
+		if val, err = in.bufParseInt(); err != nil {
 			return nil, err
 		}
 	}
 	if in.Type == InputNumericFloat {
-		if val, err = strconv.ParseFloat(string(in.buf), 64); err != nil {
+		if val, err = in.bufParseFloat(); err != nil {
 			return nil, err
 		}
 	}
@@ -118,46 +162,96 @@ func (in *Input) ValueMap() (map[string]interface{}, error) {
 // not valid.
 func (in *Input) Valid() error {
 	if in.Type == InputNumericInt {
-		if _, err := strconv.Atoi(string(in.buf)); err != nil {
+		if _, err := in.bufParseInt(); err != nil {
 			return errors.New("Expecting number of minutes e.g. 60")
 		}
 	}
 	if in.Type == InputNumericFloat {
-		if _, err := strconv.ParseFloat(string(in.buf), 64); err != nil {
-			return errors.New("Expecting decimal e.g. 50.65")
+		if _, err := in.bufParseFloat(); err != nil {
+			return errors.New("Expecting decimal e.g. 50.65 or 50,65")
 		}
 	}
 	return nil
 }
 
+// bufValue returns the text of the buffer with a decimal comma replaced by a
+// decimal point.
+func (in *Input) bufValue() string {
+	return strings.Replace(string(in.buf), ",", ".", 1)
+}
+
 func (in *Input) bufParseFloat() (float64, error) {
-	return strconv.ParseFloat(string(in.buf), 64)
+	return strconv.ParseFloat(in.bufValue(), 64)
 }
 
 func (in *Input) bufParseInt() (int, error) {
-	return strconv.Atoi(string(in.buf))
+	return strconv.Atoi(in.bufValue())
 }
 
-func (in *Input) bufAppend(r rune) {
+func (in *Input) bufInsert(r rune) {
+	if in.all {
+		in.bufClear()
+	}
 	if len(in.buf) < cap(in.buf) {
-		in.buf = append(in.buf, r)
-		in.cur.x++
-		in.cur.i++
-		setCursor(in.cur.x, in.cur.y)
+		i := in.cur.i
+		in.buf = append(in.buf, 0)
+		copy(in.buf[i+1:], in.buf[i:])
+		in.buf[i] = r
+		in.bufMove(i + 1)
 	}
 }
 
 func (in *Input) bufBackspace() {
-	if len(in.buf) > 0 {
-		in.buf = in.buf[0 : len(in.buf)-1]
-		in.cur.x--
-		in.cur.i--
-		setCursor(in.cur.x, in.cur.y)
+	if in.all {
+		in.bufClear()
+		return
+	}
+	if i := in.cur.i; i > 0 {
+		in.buf = append(in.buf[:i-1], in.buf[i:]...)
+		in.bufMove(i - 1)
 	}
 }
 
+func (in *Input) bufDelete() {
+	if in.all {
+		in.bufClear()
+		return
+	}
+	if i := in.cur.i; i < len(in.buf) {
+		in.buf = append(in.buf[:i], in.buf[i+1:]...)
+	}
+}
+
+func (in *Input) bufClear() {
+	in.buf = in.buf[:0]
+	in.bufMove(0)
+}
+
+// bufMove moves the cursor before the rune at index i, as long as it stays
+// within the buffer, and deselects the text. The cursor is drawn by SetBuf.
+func (in *Input) bufMove(i int) {
+	in.all = false
+	if i < 0 || i > len(in.buf) {
+		return
+	}
+	in.cur.i = i
+	in.cur.x = in.TextStartX() + i
+}
+
+// bufShow draws the buffer in the input, in reverse colors if it is
+// selected.
 func (in Input) bufShow() {
-	in.SetText(string(in.buf))
+	in.ClearText()
+	attr := termbox.ColorDefault
+	if in.all {
+		attr |= termbox.AttrReverse
+	}
+	mu.Lock()
+	for i, r := range in.buf {
+		termbox.SetCell(in.TextStartX()+i, in.TextY(), r, attr, attr)
+	}
+	mu.Unlock()
+	flush()
 }
 
 // NewInput returns a new Input.
@@ -229,7 +323,6 @@ func (in Input) Draw() {
 	x := in.X
 	y := in.Y
 	lw := in.LabelW
-	lt := in.LabelT
 	w := in.W
 	t := in.T
 
@@ -237,7 +330,7 @@ func (in Input) Draw() {
 	fill(x, y+1, 1, 1, '│')
 	fill(x, y+2, 1, 1, '└')
 	fill(x+1, y+0, lw, 1, '─')
-	text(x+1, y+1, lt)
+	in.drawLabel()
 	fill(x+1, y+2, lw, 1, '─')
 	fill(x+lw+1, y+0, 1, 1, '┐')
 	fill(x+lw+1, y+1, 1, 1, '│')
@@ -257,6 +350,94 @@ func (in Input) Draw() {
 	}
 }
 
+// drawLabel draws the label of the input, in reverse colors if the input has
+// the focus.
+func (in Input) drawLabel() {
+	attr := termbox.ColorDefault
+	if in.Focused() {
+		attr |= termbox.AttrReverse
+	}
+	mu.Lock()
+	label := []rune(in.LabelT)
+	for i := 0; i < in.LabelW; i++ {
+		r := ' '
+		if i < len(label) {
+			r = label[i]
+		}
+		termbox.SetCell(in.X+1+i, in.TextY(), r, attr, attr)
+	}
+	mu.Unlock()
+}
+
+// Focused returns true if the input has the keyboard focus.
+func (in Input) Focused() bool {
+	return focus >= 0 && focus < len(inputs) && inputs[focus].Field == in.Field
+}
+
+// FocusedInput returns the input that has the keyboard focus.
+func FocusedInput() *Input {
+	if focus < 0 || focus >= len(inputs) {
+		return nil
+	}
+	return inputs[focus]
+}
+
+// MoveFocus moves the keyboard focus by n inputs, forwards or backwards,
+// wrapping around. If no input has the focus, it goes to the first or the
+// last input.
+func MoveFocus(n int) {
+	if len(inputs) == 0 {
+		return
+	}
+	i := focus
+	switch {
+	case i < 0 && n > 0:
+		i = 0
+	case i < 0:
+		i = len(inputs) - 1
+	default:
+		i = ((i+n)%len(inputs) + len(inputs)) % len(inputs)
+	}
+	setFocus(i)
+	in := inputs[i]
+	switch {
+	case in.Field == configPreset:
+		UpdateText("Enter next preset, Ctrl-S save as, F2 rename, Del delete.")
+	case in.Type == InputSwitch:
+		UpdateText(fmt.Sprintf("Press 'space' or 'Enter' to switch %v, 'Esc' to leave.", in.LabelT))
+	default:
+		UpdateText(fmt.Sprintf("Press 'Enter' to change %v, 'Esc' to leave.", in.LabelT))
+	}
+}
+
+// ClearFocus removes the keyboard focus from the inputs.
+func ClearFocus() {
+	setFocus(-1)
+}
+
+func setFocus(i int) {
+	prev := FocusedInput()
+	focus = i
+	if prev != nil {
+		prev.drawLabel()
+	}
+	if in := FocusedInput(); in != nil {
+		in.drawLabel()
+	}
+	flush()
+}
+
+// Activate starts editing a numeric input or switches a switch input to its
+// next value.
+func (in *Input) Activate() error {
+	if in.Type == InputSwitch {
+		DeselectAllInputs()
+		return in.Switch()
+	}
+	in.SetSelected(true)
+	return nil
+}
+
 // Selected returns true of the input is selected.
 func (in Input) Selected() bool {
 	return in.s
@@ -306,6 +487,22 @@ func (in *Input) SetSelected(selected bool) {
 	flush()
 }
 
+// LockInputs locks or unlocks all inputs. Locked inputs cannot be selected
+// or switched so that the configuration does not change while a session is
+// running.
+func LockInputs(locked bool) {
+	mu.Lock()
+	inputsLocked = locked
+	mu.Unlock()
+}
+
+// InputsLocked returns true if the inputs are locked.
+func InputsLocked() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return inputsLocked
+}
+
 // SelectedInput returns the input that is currently selected.
 func SelectedInput() *Input {
 	var si *Input
@@ -326,12 +523,19 @@ func DeselectAllInputs() {
 }
 
 // Entry represents a key entry that an input accpets. These include a
-// character, backspace, delete and enter.
+// character, backspace, delete, enter, the keys that move the cursor and the
+// keys that select all and clear the text.
 type Entry struct {
 	Ch        rune
 	Backspace bool
 	Delete    bool
 	Enter     bool
+	Left      bool
+	Right     bool
+	Home      bool
+	End       bool
+	SelectAll bool
+	Clear     bool
 }
 
 // String returns a string representation of the entry.
@@ -345,23 +549,40 @@ func (e Entry) String() string {
 	if e.Enter {
 		return fmt.Sprintf("Entry = 'Enter'")
 	}
+	if e.Left || e.Right || e.Home || e.End {
+		return fmt.Sprintf("Entry = 'Cursor'")
+	}
+	if e.SelectAll {
+		return fmt.Sprintf("Entry = 'SelectAll'")
+	}
+	if e.Clear {
+		return fmt.Sprintf("Entry = 'Clear'")
+	}
 	return fmt.Sprintf("Entry = %v", rtoa(e.Ch))
 
 }
 
 // NewEntry returns a new Entry based on a termbox event received.
 func NewEntry(te termbox.Event) *Entry {
-	if te.Key == termbox.KeyEnter {
+	switch te.Key {
+	case termbox.KeyEnter:
 		return &Entry{Enter: true}
-	}
-	if te.Key == termbox.KeyDelete {
+	case termbox.KeyDelete:
 		return &Entry{Delete: true}
-	}
-	if te.Key == termbox.KeyBackspace {
-		return &Entry{Backspace: true}
-	}
-	if te.Key == termbox.KeyBackspace2 {
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
 		return &Entry{Backspace: true}
+	case termbox.KeyArrowLeft:
+		return &Entry{Left: true}
+	case termbox.KeyArrowRight:
+		return &Entry{Right: true}
+	case termbox.KeyHome:
+		return &Entry{Home: true}
+	case termbox.KeyEnd:
+		return &Entry{End: true}
+	case termbox.KeyCtrlA:
+		return &Entry{SelectAll: true}
+	case termbox.KeyCtrlU:
+		return &Entry{Clear: true}
 	}
 	if AllowedEntry(te) {
 		return &Entry{Ch: te.Ch}
@@ -370,15 +591,20 @@ func NewEntry(te termbox.Event) *Entry {
 }
 
 // AllowedEntry returns true if the termbox event received is a valid entry
-// for the input. These include 0-9, '.', delete, backspaces and enter.
+// for the input. These include 0-9, '.', ',' (a decimal comma), delete,
+// backspaces, enter, the left and right arrows, home, end, Ctrl-A which
+// selects all the text and Ctrl-U which clears it.
 func AllowedEntry(te termbox.Event) bool {
-	if te.Key == termbox.KeyDelete || te.Key == termbox.KeyBackspace || te.Key == termbox.KeyBackspace2 || te.Key == termbox.KeyEnter {
+	switch te.Key {
+	case termbox.KeyDelete, termbox.KeyBackspace, termbox.KeyBackspace2, termbox.KeyEnter,
+		termbox.KeyArrowLeft, termbox.KeyArrowRight, termbox.KeyHome, termbox.KeyEnd,
+		termbox.KeyCtrlA, termbox.KeyCtrlU:
 		return true
 	}
 	key := te.Ch
 	if key == '0' || key == '1' || key == '2' || key == '3' || key == '4' ||
 		key == '5' || key == '6' || key == '7' || key == '8' || key == '9' ||
-		key == '.' {
+		key == '.' || key == ',' {
 		return true
 	}
 	return false
@@ -0,0 +1,139 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/nsf/termbox-go"
+)
+
+// typed returns an entry for each rune of s.
+func typed(s string) []*Entry {
+	var es []*Entry
+	for _, r := range s {
+		es = append(es, &Entry{Ch: r})
+	}
+	return es
+}
+
+func entries(groups ...[]*Entry) []*Entry {
+	var es []*Entry
+	for _, g := range groups {
+		es = append(es, g...)
+	}
+	return es
+}
+
+func TestInputBufEdit(t *testing.T) {
+	var (
+		left      = []*Entry{{Left: true}}
+		right     = []*Entry{{Right: true}}
+		home      = []*Entry{{Home: true}}
+		end       = []*Entry{{End: true}}
+		backspace = []*Entry{{Backspace: true}}
+		del       = []*Entry{{Delete: true}}
+		selectAll = []*Entry{{SelectAll: true}}
+		clear     = []*Entry{{Clear: true}}
+	)
+	tests := []struct {
+		name       string
+		bufW       int
+		entries    []*Entry
+		wantText   string
+		wantCursor int
+		wantAll    bool
+	}{
+		{"type", 5, typed("12"), "12", 2, false},
+		{"insert in the middle", 5, entries(typed("123"), left, left, typed("9")), "1923", 2, false},
+		{"left at the start", 5, entries(typed("12"), home, left), "12", 0, false},
+		{"right at the end", 5, entries(typed("12"), right), "12", 2, false},
+		{"home and end", 5, entries(typed("123"), home, right, end), "123", 3, false},
+		{"backspace at the start", 5, entries(typed("12"), home, backspace), "12", 0, false},
+		{"backspace in the middle", 5, entries(typed("123"), left, backspace), "13", 1, false},
+		{"delete at the end", 5, entries(typed("12"), del), "12", 2, false},
+		{"delete at the start", 5, entries(typed("123"), home, del), "23", 0, false},
+		{"full", 5, typed("1234567"), "12345", 5, false},
+		{"full insert in the middle", 3, entries(typed("123"), left, typed("9")), "123", 2, false},
+		{"select all", 5, entries(typed("12"), selectAll), "12", 2, true},
+		{"select all of nothing", 5, selectAll, "", 0, false},
+		{"select all and type", 5, entries(typed("12"), selectAll, typed("7")), "7", 1, false},
+		{"select all and backspace", 5, entries(typed("12"), selectAll, backspace), "", 0, false},
+		{"select all and delete", 5, entries(typed("12"), selectAll, del), "", 0, false},
+		{"select all and move", 5, entries(typed("12"), selectAll, left), "12", 1, false},
+		{"clear", 5, entries(typed("123"), left, clear), "", 0, false},
+		{"multi-byte runes", 5, entries(typed("é€1"), left, left, backspace), "€1", 0, false},
+		{"multi-byte runes when full", 2, typed("é€1"), "é€", 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := &Input{bufW: tt.bufW}
+			in.ClearBuf()
+			for _, e := range tt.entries {
+				in.bufEdit(e)
+			}
+			if got := string(in.buf); got != tt.wantText {
+				t.Errorf("text = %q, want %q", got, tt.wantText)
+			}
+			if in.cur.i != tt.wantCursor || in.cur.x != in.TextStartX()+tt.wantCursor {
+				t.Errorf("cursor = %d at x %d, want %d at x %d", in.cur.i, in.cur.x, tt.wantCursor, in.TextStartX()+tt.wantCursor)
+			}
+			if in.all != tt.wantAll {
+				t.Errorf("all = %v, want %v", in.all, tt.wantAll)
+			}
+		})
+	}
+}
+
+func TestInputBufValue(t *testing.T) {
+	tests := []struct {
+		text      string
+		wantValue string
+		wantFloat float64
+		wantErr   bool
+	}{
+		{"12.5", "12.5", 12.5, false},
+		{"12,5", "12.5", 12.5, false},
+		{",5", ".5", 0.5, false},
+		{"1,2,5", "1.2,5", 0, true},
+		{"1,2.5", "1.2.5", 0, true},
+		{"", "", 0, true},
+	}
+	for _, tt := range tests {
+		in := &Input{bufW: 5}
+		in.ClearBuf()
+		in.buf = append(in.buf, []rune(tt.text)...)
+		if got := in.bufValue(); got != tt.wantValue {
+			t.Errorf("bufValue() of %q = %q, want %q", tt.text, got, tt.wantValue)
+		}
+		f, err := in.bufParseFloat()
+		if (err != nil) != tt.wantErr || f != tt.wantFloat {
+			t.Errorf("bufParseFloat() of %q = %v, %v, want %v, error %v", tt.text, f, err, tt.wantFloat, tt.wantErr)
+		}
+	}
+}
+
+func TestNewEntry(t *testing.T) {
+	tests := []struct {
+		ev   termbox.Event
+		want *Entry
+	}{
+		{termbox.Event{Ch: '5'}, &Entry{Ch: '5'}},
+		{termbox.Event{Ch: ','}, &Entry{Ch: ','}},
+		{termbox.Event{Ch: '.'}, &Entry{Ch: '.'}},
+		{termbox.Event{Ch: 'q'}, nil},
+		{termbox.Event{Ch: 'é'}, nil},
+		{termbox.Event{Key: termbox.KeyCtrlA}, &Entry{SelectAll: true}},
+		{termbox.Event{Key: termbox.KeyCtrlU}, &Entry{Clear: true}},
+		{termbox.Event{Key: termbox.KeyBackspace2}, &Entry{Backspace: true}},
+		{termbox.Event{Key: termbox.KeyHome}, &Entry{Home: true}},
+		{termbox.Event{Key: termbox.KeySpace}, nil},
+	}
+	for _, tt := range tests {
+		got := NewEntry(tt.ev)
+		if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+			t.Errorf("NewEntry(%+v) = %v, want %v", tt.ev, got, tt.want)
+		}
+		if allowed := AllowedEntry(tt.ev); allowed != (tt.want != nil) {
+			t.Errorf("AllowedEntry(%+v) = %v, want %v", tt.ev, allowed, tt.want != nil)
+		}
+	}
+}
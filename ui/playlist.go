@@ -0,0 +1,122 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/nstratos/mdt/session"
+)
+
+// Playlist is an ordered list of presets that a session runs one after the
+// other, e.g. a descent, a hold and a return.
+type Playlist struct {
+	Name  string
+	Parts []PlaylistPart
+}
+
+// PlaylistPart is a preset of a playlist that runs after a gap of Gap
+// minutes.
+type PlaylistPart struct {
+	Preset string
+	Gap    int `json:",omitempty"`
+}
+
+func validatePlaylists(playlists []Playlist, active string) error {
+	seen := make(map[string]bool)
+	for _, pl := range playlists {
+		if pl.Name == "" {
+			return errors.New("Playlists must have a name")
+		}
+		if seen[pl.Name] {
+			return fmt.Errorf("Playlist %q exists more than once", pl.Name)
+		}
+		seen[pl.Name] = true
+		if len(pl.Parts) == 0 {
+			return fmt.Errorf("Playlist %q must have at least one part", pl.Name)
+		}
+		for i, part := range pl.Parts {
+			if part.Preset == "" {
+				return fmt.Errorf("Part %d of playlist %q must have a preset", i+1, pl.Name)
+			}
+			if part.Gap < 0 {
+				return fmt.Errorf("Part %d of playlist %q must not have a negative gap", i+1, pl.Name)
+			}
+		}
+	}
+	if active != "" && !seen[active] {
+		return fmt.Errorf("Playlist %q does not exist", active)
+	}
+	return nil
+}
+
+// nextPlaylist returns the playlist that follows the active one. The cycle
+// goes through no playlist after the last one.
+func nextPlaylist(playlists []Playlist, active string) string {
+	if active == "" {
+		if len(playlists) == 0 {
+			return ""
+		}
+		return playlists[0].Name
+	}
+	for i, pl := range playlists {
+		if pl.Name == active && i+1 < len(playlists) {
+			return playlists[i+1].Name
+		}
+	}
+	return ""
+}
+
+func (c Config) playlist(name string) (Playlist, bool) {
+	for _, pl := range c.Playlists {
+		if pl.Name == name {
+			return pl, true
+		}
+	}
+	return Playlist{}, false
+}
+
+// PlaylistSession returns the configuration of a session that runs the
+// active playlist. Each part runs with the values of its preset and the
+// keys, bands and precision of the configuration. It returns an error if a
+// preset of the playlist cannot be loaded or is not valid.
+func (c Config) PlaylistSession() (session.Config, error) {
+	pl, ok := c.playlist(c.Playlist)
+	if !ok {
+		return session.Config{}, fmt.Errorf("Playlist %q does not exist", c.Playlist)
+	}
+	var parts []session.Part
+	for i, part := range pl.Parts {
+		p, err := LoadPreset(part.Preset)
+		if os.IsNotExist(err) {
+			return session.Config{}, fmt.Errorf("Preset %q of playlist %q does not exist", part.Preset, pl.Name)
+		}
+		if err != nil {
+			return session.Config{}, err
+		}
+		pc := c
+		pc.applyPreset(part.Preset, p)
+		if err := pc.Validate(); err != nil {
+			return session.Config{}, fmt.Errorf("Part %d of playlist %q is invalid (%v)", i+1, pl.Name, err)
+		}
+		parts = append(parts, session.Part{
+			Gap:    time.Duration(part.Gap) * time.Minute,
+			Config: pc.Session(),
+		})
+	}
+	return session.NewPlaylist(pl.Name, parts, c.Session()), nil
+}
+
+// PlaylistText returns a description of the active playlist for the status
+// bar.
+func (c Config) PlaylistText() string {
+	if c.Playlist == "" {
+		return "No playlist, sessions run the values below."
+	}
+	sc, err := c.PlaylistSession()
+	if err != nil {
+		return fmt.Sprintf("%v", err)
+	}
+	return fmt.Sprintf("Playlist %v: %d parts, %v min.", sc.Playlist, len(sc.Parts), sc.TotalTime.Minutes())
+}
@@ -0,0 +1,322 @@
+package ui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/nsf/termbox-go"
+	"github.com/nstratos/mdt/session"
+)
+
+const (
+	presetsFolder = "presets"
+	presetExt     = ".json"
+	maxPresetName = 32
+	noPreset      = "none"
+)
+
+// Keys that act on the presets while the Preset input has the focus.
+const (
+	KeyPresetSaveAs = termbox.KeyCtrlS
+	KeyPresetRename = termbox.KeyF2
+	KeyPresetDelete = termbox.KeyDelete
+)
+
+// Preset holds the values of the configuration that make up a routine, e.g.
+// a morning session from 15 to 8 Hz. The keys, the bands and the output of
+// the logs are shared by all presets.
+type Preset struct {
+	Mode        string
+	TotalTime   int
+	Offset      int
+	StartBaseHz float64
+	EndBaseHz   float64
+	StartHz     float64
+	EndHz       float64
+	Program     session.Program
+	Curve       session.Curve
+	Targets     []float64
+}
+
+func (c Config) preset() Preset {
+	return Preset{
+		Mode:        c.Mode,
+		TotalTime:   c.TotalTime,
+		Offset:      c.Offset,
+		StartBaseHz: c.StartBaseHz,
+		EndBaseHz:   c.EndBaseHz,
+		StartHz:     c.StartHz,
+		EndHz:       c.EndHz,
+		Program:     c.Program,
+		Curve:       c.Curve,
+		Targets:     c.Targets,
+	}
+}
+
+// applyPreset sets the values of a preset named name to the configuration.
+func (c *Config) applyPreset(name string, p Preset) {
+	c.Preset = name
+	c.Mode = p.Mode
+	c.TotalTime = p.TotalTime
+	c.Offset = p.Offset
+	c.StartBaseHz = p.StartBaseHz
+	c.EndBaseHz = p.EndBaseHz
+	c.StartHz = p.StartHz
+	c.EndHz = p.EndHz
+	c.Program = p.Program
+	c.Curve = p.Curve
+	c.Targets = p.Targets
+	if c.Curve == "" {
+		c.Curve = defaultConfig.Curve
+	}
+}
+
+func presetsPath() (string, error) {
+	u, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	if err := createConfigFolderIfNotExist(); err != nil {
+		return "", err
+	}
+	path := filepath.Join(u.HomeDir, configFolder, presetsFolder)
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func presetPath(name string) (string, error) {
+	dir, err := presetsPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+presetExt), nil
+}
+
+// ValidPresetName returns an error if a preset name is empty, too long or
+// has characters other than letters, digits, spaces, '-', '_' and '.'.
+func ValidPresetName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("Preset name must not be empty")
+	}
+	if len([]rune(name)) > maxPresetName {
+		return fmt.Errorf("Preset name must not be longer than %d characters", maxPresetName)
+	}
+	if strings.HasPrefix(name, ".") || name == noPreset {
+		return fmt.Errorf("Preset name %q is not allowed", name)
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune(" -_.", r) {
+			return fmt.Errorf("Preset name must not contain %q", r)
+		}
+	}
+	return nil
+}
+
+// Presets returns the names of the saved presets, sorted.
+func Presets() ([]string, error) {
+	dir, err := presetsPath()
+	if err != nil {
+		return nil, err
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, fi := range files {
+		if !fi.IsDir() && filepath.Ext(fi.Name()) == presetExt {
+			names = append(names, strings.TrimSuffix(fi.Name(), presetExt))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// LoadPreset loads the preset named name.
+func LoadPreset(name string) (Preset, error) {
+	var p Preset
+	path, err := presetPath(name)
+	if err != nil {
+		return p, err
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal(b, &p); err != nil {
+		return p, fmt.Errorf("loading preset %q error: %v", name, err)
+	}
+	return p, nil
+}
+
+// SavePreset saves the values of the configuration as the preset named name,
+// replacing it if it exists.
+func SavePreset(name string, c Config) error {
+	b, err := json.MarshalIndent(c.preset(), "", "  ")
+	if err != nil {
+		return err
+	}
+	path, err := presetPath(name)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+func presetExists(name string) bool {
+	path, err := presetPath(name)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// SelectPreset makes the preset named name the active one and copies its
+// values to the configuration. An empty name keeps the values and leaves
+// the configuration without an active preset.
+func SelectPreset(name string) error {
+	c := GetConfig()
+	if name == "" {
+		c.Preset = ""
+	} else {
+		p, err := LoadPreset(name)
+		if err != nil {
+			return err
+		}
+		c.applyPreset(name, p)
+		if err := c.Validate(); err != nil {
+			return fmt.Errorf("Invalid preset %q (%v)", name, err)
+		}
+	}
+	if err := writeConfig(c); err != nil {
+		return err
+	}
+	UpdateConfig(c)
+	ReloadInputs(c)
+	return nil
+}
+
+// nextPreset returns the preset that follows the active one. The cycle goes
+// through no preset after the last one.
+func nextPreset(active string) (string, error) {
+	names, err := Presets()
+	if err != nil {
+		return "", err
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("No presets yet, press Ctrl-S on Preset to save one")
+	}
+	if active == "" {
+		return names[0], nil
+	}
+	for i, name := range names {
+		if name == active && i+1 < len(names) {
+			return names[i+1], nil
+		}
+		if name > active {
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
+// PresetAction handles a key pressed while the Preset input has the focus
+// and is not edited. Ctrl-S saves the configuration as a new preset, F2
+// renames the active preset and Delete deletes it, each asking for a name or
+// a confirmation first. It returns false if the key is not a preset action.
+func PresetAction(key termbox.Key) bool {
+	in := FocusedInput()
+	if in == nil || in.Field != configPreset || SelectedInput() != nil {
+		return false
+	}
+	active := GetConfig().Preset
+	switch key {
+	case KeyPresetSaveAs:
+		startPrompt("Save preset as: ", "", func(name string) error {
+			if err := ValidPresetName(name); err != nil {
+				return err
+			}
+			if presetExists(name) {
+				return fmt.Errorf("Preset %q exists", name)
+			}
+			if err := SavePreset(name, GetConfig()); err != nil {
+				return err
+			}
+			if err := SelectPreset(name); err != nil {
+				return err
+			}
+			UpdateText(fmt.Sprintf("Preset %q saved.", name))
+			return nil
+		})
+	case KeyPresetRename:
+		if active == "" {
+			UpdateText("No active preset to rename.")
+			return true
+		}
+		startPrompt("Rename preset to: ", active, func(name string) error {
+			if err := ValidPresetName(name); err != nil {
+				return err
+			}
+			if name == active {
+				return nil
+			}
+			if presetExists(name) {
+				return fmt.Errorf("Preset %q exists", name)
+			}
+			from, err := presetPath(active)
+			if err != nil {
+				return err
+			}
+			to, err := presetPath(name)
+			if err != nil {
+				return err
+			}
+			if err := os.Rename(from, to); err != nil {
+				return err
+			}
+			if err := SelectPreset(name); err != nil {
+				return err
+			}
+			UpdateText(fmt.Sprintf("Preset %q renamed to %q.", active, name))
+			return nil
+		})
+	case KeyPresetDelete:
+		if active == "" {
+			UpdateText("No active preset to delete.")
+			return true
+		}
+		startPrompt(fmt.Sprintf("Delete preset %q? (y/n) ", active), "", func(answer string) error {
+			if answer != "y" {
+				UpdateText("Preset not deleted.")
+				return nil
+			}
+			path, err := presetPath(active)
+			if err != nil {
+				return err
+			}
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			// The values of the deleted preset stay in the configuration.
+			if err := SelectPreset(""); err != nil {
+				return err
+			}
+			UpdateText(fmt.Sprintf("Preset %q deleted.", active))
+			return nil
+		})
+	default:
+		return false
+	}
+	return true
+}
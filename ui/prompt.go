@@ -0,0 +1,77 @@
+package ui
+
+import (
+	"fmt"
+	"unicode"
+
+	"github.com/nsf/termbox-go"
+)
+
+// prompt asks for a line of text in the status bar.
+type prompt struct {
+	label string
+	buf   []rune
+	// done is called with the text when Enter is pressed. If it returns an
+	// error, the error is shown and the prompt stays open.
+	done func(string) error
+}
+
+// activePrompt is the prompt that is waiting for text, if any.
+var activePrompt *prompt
+
+func startPrompt(label, text string, done func(string) error) {
+	activePrompt = &prompt{label: label, buf: []rune(text), done: done}
+	activePrompt.show()
+}
+
+func (p *prompt) show() {
+	t := p.label + string(p.buf)
+	UpdateText(t)
+	if statusBar != nil {
+		setCursor(statusBar.X+statusBar.timerWidth+2+len([]rune(t)), statusBar.Y+1)
+		flush()
+	}
+}
+
+// Prompting returns true while a prompt is waiting for text. All keys go to
+// the prompt until it is closed.
+func Prompting() bool {
+	return activePrompt != nil
+}
+
+// PromptKey handles a key pressed while prompting. Enter accepts the text and
+// Esc cancels the prompt.
+func PromptKey(ev termbox.Event) {
+	p := activePrompt
+	if p == nil {
+		return
+	}
+	switch {
+	case ev.Key == termbox.KeyEsc:
+		closePrompt()
+		ResetText()
+	case ev.Key == termbox.KeyEnter:
+		if err := p.done(string(p.buf)); err != nil {
+			UpdateText(fmt.Sprintf("%v", err))
+			return
+		}
+		closePrompt()
+	case ev.Key == termbox.KeyBackspace || ev.Key == termbox.KeyBackspace2:
+		if len(p.buf) > 0 {
+			p.buf = p.buf[:len(p.buf)-1]
+		}
+		p.show()
+	case ev.Key == termbox.KeySpace:
+		p.buf = append(p.buf, ' ')
+		p.show()
+	case ev.Ch != 0 && unicode.IsPrint(ev.Ch):
+		p.buf = append(p.buf, ev.Ch)
+		p.show()
+	}
+}
+
+func closePrompt() {
+	activePrompt = nil
+	termbox.HideCursor()
+	flush()
+}
@@ -1,21 +1,13 @@
 package ui
 
 import (
+	"fmt"
 	"sync"
 
 	"github.com/nsf/termbox-go"
+	"github.com/nstratos/mdt/session"
 )
 
-// Labels is a map of each allowed key press (rune) to it's full string label.
-var Labels = map[rune]string{
-	'a': "Visual imagination",
-	'd': "Language thought",
-	'e': "Language voice",
-	'q': "Visual memory",
-	's': "Auditory imagination",
-	'w': "Auditory memory",
-}
-
 const title = `           _ _   
   _ __  __| | |_ 
  | '  \/ _  |  _|
@@ -31,10 +23,18 @@ var (
 	keys      []*KeyLabel
 	statusBar *StatusBar
 	config    Config
+	// inputsLocked is true while a session is running.
+	inputsLocked bool
+	// focus is the index of the input that has the keyboard focus or -1 if
+	// none has.
+	focus = -1
+	// openKeys holds the keys whose interval is open, which stay
+	// highlighted when the screen is redrawn.
+	openKeys = make(map[rune]bool)
 )
 
 const (
-	inputLabelWidth      = 10
+	inputLabelWidth      = 11
 	inputWidth           = 10
 	inputMinutesBufWidth = 3
 	inputHzBufWidth      = 5
@@ -60,7 +60,13 @@ func Init(version string) error {
 // Loading configuration from config.json
 func initConfig() error {
 	config = Config{}
-	return config.Load()
+	if err := config.Load(); err != nil {
+		return err
+	}
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %v", err)
+	}
+	return nil
 }
 
 // Initializing termbox
@@ -71,12 +77,14 @@ func initTermbox() error {
 	}
 	termbox.SetInputMode(termbox.InputEsc | termbox.InputMouse)
 	termbox.SetOutputMode(termbox.OutputNormal)
+	enablePaste()
 	return termbox.Clear(termbox.ColorWhite, termbox.ColorDefault)
 }
 
 // Close should be deferred after initialization. It finalizes termbox library.
 func Close() {
 	mu.Lock()
+	disablePaste()
 	termbox.Close()
 	mu.Unlock()
 }
@@ -89,7 +97,10 @@ func DrawAll() {
 	statusBar = nil
 	_, keysY := drawTitle(0, 0, Version)
 	keysX, sbY := drawInputs(0, keysY+1)
-	_, _ = drawKeys(keysX+3, keysY+1)
+	_, keysMaxY := drawKeys(keysX+3, keysY+1)
+	if keysMaxY > sbY {
+		sbY = keysMaxY
+	}
 	_, _ = drawStatusBar(0, sbY+1)
 }
 
@@ -110,33 +121,35 @@ func drawStatusBar(x, y int) (maxX, maxY int) {
 	return statusBar.MaxX(), statusBar.MaxY()
 }
 
+// drawInputs draws the inputs in two columns so that the screen still fits
+// in 80x24. Focus moves down the first column and then down the second.
 func drawInputs(x, y int) (maxX, maxY int) {
 	const lw = inputLabelWidth
 	const w = inputWidth
-	in1 := NewInput(x, y+0, lw, "Mode", w, 0, config.ModeS(), false, InputSwitch, configMode)
-	in2 := NewInput(x, y+2, lw, "TotalTime", w, inputMinutesBufWidth, config.TotalTimeS(), true, InputNumericInt, configTotalTime)
-	in3 := NewInput(x, y+4, lw, "Offset", w, inputMinutesBufWidth, config.OffsetS(), true, InputNumericInt, configOffset)
-	in4 := NewInput(x, y+6, lw, "BaseHz", w, inputHzBufWidth, config.BaseHzS(), true, InputNumericFloat, configBaseHz)
-	in5 := NewInput(x, y+8, lw, "StartHz", w, inputHzBufWidth, config.StartHzS(), true, InputNumericFloat, configStartHz)
-	in6 := NewInput(x, y+10, lw, "EndHz", w, inputHzBufWidth, config.EndHzS(), true, InputNumericFloat, configEndHz)
+	x2 := x + lw + 3 + w + 2
+	in0 := NewInput(x, y+0, lw, "Preset", w, 0, config.PresetS(), false, InputSwitch, configPreset)
+	in1 := NewInput(x, y+2, lw, "Playlist", w, 0, config.PlaylistS(), true, InputSwitch, configPlaylist)
+	in2 := NewInput(x, y+4, lw, "Mode", w, 0, config.ModeS(), true, InputSwitch, configMode)
+	in3 := NewInput(x, y+6, lw, "Curve", w, 0, config.CurveS(), true, InputSwitch, configCurve)
+	in4 := NewInput(x, y+8, lw, "TotalTime", w, inputMinutesBufWidth, config.TotalTimeS(), true, InputNumericInt, configTotalTime)
+	in5 := NewInput(x2, y+0, lw, "Offset", w, inputMinutesBufWidth, config.OffsetS(), false, InputNumericInt, configOffset)
+	in6 := NewInput(x2, y+2, lw, "StartBaseHz", w, inputHzBufWidth, config.StartBaseHzS(), true, InputNumericFloat, configStartBaseHz)
+	in7 := NewInput(x2, y+4, lw, "EndBaseHz", w, inputHzBufWidth, config.EndBaseHzS(), true, InputNumericFloat, configEndBaseHz)
+	in8 := NewInput(x2, y+6, lw, "StartHz", w, inputHzBufWidth, config.StartHzS(), true, InputNumericFloat, configStartHz)
+	in9 := NewInput(x2, y+8, lw, "EndHz", w, inputHzBufWidth, config.EndHzS(), true, InputNumericFloat, configEndHz)
 	inputs = nil
-	inputs = append(inputs, in1, in2, in3, in4, in5, in6)
+	inputs = append(inputs, in0, in1, in2, in3, in4, in5, in6, in7, in8, in9)
 	for _, in := range inputs {
 		in.Draw()
 	}
-	return in6.MaxX(), in6.MaxY()
+	return in9.MaxX(), in9.MaxY()
 }
 
 // ReloadInputs updates each input with the values of a new configuration.
 // It should be called after receiving a valid value from en enabled input.
 func ReloadInputs(c Config) {
-	inputs[0].T = c.ModeS()
-	inputs[1].T = c.TotalTimeS()
-	inputs[2].T = c.OffsetS()
-	inputs[3].T = c.BaseHzS()
-	inputs[4].T = c.StartHzS()
-	inputs[5].T = c.EndHzS()
 	for _, in := range inputs {
+		in.T = c.FieldS(in.Field)
 		in.ClearBuf()
 		in.ResetText()
 	}
@@ -145,17 +158,16 @@ func ReloadInputs(c Config) {
 func drawKeys(x, y int) (maxX, maxY int) {
 	const lw = keyLabelWidth
 	const w = keyWidth
-	k1 := NewKeyLabel(x, y+0, lw, rtoa('q'), w, Labels['q'], false)
-	k2 := NewKeyLabel(x, y+2, lw, rtoa('a'), w, Labels['a'], true)
-	k3 := NewKeyLabel(x, y+4, lw, rtoa('w'), w, Labels['w'], true)
-	k4 := NewKeyLabel(x, y+6, lw, rtoa('s'), w, Labels['s'], true)
-	k5 := NewKeyLabel(x, y+8, lw, rtoa('e'), w, Labels['e'], true)
-	k6 := NewKeyLabel(x, y+10, lw, rtoa('d'), w, Labels['d'], true)
-	keys = append(keys, k1, k2, k3, k4, k5, k6)
-	for _, k := range keys {
+	// Each key is drawn below the previous one in the configured order.
+	maxX, maxY = x, y
+	for i, b := range config.Keys {
+		k := NewKeyLabel(x, y+i*2, lw, rtoa(b.Rune()), w, b.Label, i != 0)
+		k.S = openKeys[b.Rune()]
+		keys = append(keys, k)
 		k.Draw()
+		maxX, maxY = k.MaxX(), k.MaxY()
 	}
-	return k6.MaxX(), k6.MaxY()
+	return maxX, maxY
 }
 
 // Cell wraps a termbox cell. A single conceptual entity on the screen. A
@@ -234,7 +246,7 @@ type KeyLabel struct {
 	W      int    // rest of width
 	T      string // text describing the key
 	a      bool
-	S      bool
+	S      bool // selected, drawn in reverse colors
 }
 
 // NewKeyLabel creates a new KeyLabel.
@@ -266,13 +278,18 @@ func (kl KeyLabel) Draw() {
 	fill(x, y+2, 1, 1, '└')
 	fill(x+1, y+0, lw, 1, '─')
 
+	fill(x+1, y+1, lw, 1, ' ')
 	text(x+1, y+1, lt)
 	fill(x+1, y+2, lw, 1, '─')
 	fill(x+lw+1, y+0, 1, 1, '─')
 	fill(x+lw+1, y+1, 1, 1, ' ')
 	fill(x+lw+1, y+2, 1, 1, '─')
 	fill(x+lw+2, y+0, w, 1, '─')
+	fill(x+lw+2, y+1, w, 1, ' ')
 	text(x+lw+2, y+1, t)
+	if kl.S {
+		reverse(x+1, y+1, lw+1+w)
+	}
 	fill(x+lw+2, y+2, w, 1, '─')
 	fill(x+lw+2+w, y+0, 1, 1, '┐')
 	fill(x+lw+2+w, y+1, 1, 1, '│')
@@ -283,6 +300,26 @@ func (kl KeyLabel) Draw() {
 	}
 }
 
+// HighlightOpen highlights the labels of the keys of the open intervals and
+// only those. It should be called after each capture and correction and
+// with no intervals when a session ends.
+func HighlightOpen(open []session.Capture) {
+	openKeys = make(map[rune]bool)
+	for _, c := range open {
+		openKeys[c.Key] = true
+	}
+	for i, b := range config.Keys {
+		if i >= len(keys) {
+			break
+		}
+		if keys[i].S != openKeys[b.Rune()] {
+			keys[i].S = openKeys[b.Rune()]
+			keys[i].Draw()
+		}
+	}
+	flush()
+}
+
 // StatusBar holds the data for drawing a status bar with a specified width
 // and text. It also has space to the left for the timer.
 type StatusBar struct {
@@ -365,6 +402,35 @@ func ResetTimer() {
 	}
 }
 
+// UpdatePart shows the part of a playlist that runs, e.g. 2/3, on the top
+// border of the status bar.
+func (sb StatusBar) UpdatePart(part, parts int) {
+	t := fmt.Sprintf(" %d/%d ", part, parts)
+	sb.ResetPart()
+	text(sb.X+sb.timerWidth+2+sb.Width-len(t)-1, sb.Y, t)
+	flush()
+}
+
+// ResetPart clears the part shown on the top border of the status bar.
+func (sb StatusBar) ResetPart() {
+	fill(sb.X+sb.timerWidth+2, sb.Y, sb.Width, 1, '═')
+	flush()
+}
+
+// UpdatePart is a helper function that updates the status bar's part.
+func UpdatePart(part, parts int) {
+	if statusBar != nil {
+		statusBar.UpdatePart(part, parts)
+	}
+}
+
+// ResetPart is a helper function that clears the status bar's part.
+func ResetPart() {
+	if statusBar != nil {
+		statusBar.ResetPart()
+	}
+}
+
 // UpdateText is a helper function that updates the status bar's text.
 func UpdateText(text string) {
 	if statusBar != nil {
@@ -378,21 +444,3 @@ func ResetText() {
 		statusBar.UpdateText(statusBarDefaultText)
 	}
 }
-
-// Capture represents a captured key press at a specific second of the timer
-// along with the value of Hz that was recorded.
-type Capture struct {
-	Value   rune
-	Seconds int
-	Hz      float64
-}
-
-// Label returns the description of the captured key value.
-func (c *Capture) Label() string {
-	return Labels[c.Value]
-}
-
-// Timestamp returns the timestamp of when a capture happened.
-func (c *Capture) Timestamp() string {
-	return FormatTimer(c.Seconds)
-}